@@ -0,0 +1,22 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// adminFiles holds the browser UI served at /admin/. It only uses the JSON API, so
+// everything it can do is also available to scripts.
+//
+//go:embed admin
+var adminFiles embed.FS
+
+// adminHandler serves the embedded admin UI
+func adminHandler() http.Handler {
+	files, err := fs.Sub(adminFiles, "admin")
+	if err != nil {
+		panic(err) // The directory is embedded at build time
+	}
+	return http.StripPrefix("/admin/", http.FileServer(http.FS(files)))
+}
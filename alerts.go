@@ -0,0 +1,508 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Alert preempts the view rotation until it expires or is cleared
+type Alert struct {
+	ID        string                 `json:"id"`
+	Title     string                 `json:"title"`
+	Message   string                 `json:"message,omitempty"`
+	Priority  int                    `json:"priority"`       // Highest priority alert is shown
+	View      string                 `json:"view,omitempty"` // Configured view to show instead of the built-in alert screen
+	Data      map[string]interface{} `json:"data,omitempty"` // Extra fields for the alert template
+	Source    string                 `json:"source"`         // "api" or "view:<name>"
+	CreatedAt time.Time              `json:"createdAt"`
+	ExpiresAt time.Time              `json:"expiresAt,omitzero"` // Zero = until cleared
+}
+
+// AlertRequest is the body of POST /api/alerts
+type AlertRequest struct {
+	ID       string                 `json:"id"` // Reusing an ID replaces that alert
+	Title    string                 `json:"title"`
+	Message  string                 `json:"message"`
+	Priority int                    `json:"priority"`
+	TTLSecs  int                    `json:"ttlSeconds"` // 0 = defaultAlertTTL
+	View     string                 `json:"view"`
+	Data     map[string]interface{} `json:"data"`
+}
+
+// AlertRule raises an alert when a field in a view's data meets a condition
+type AlertRule struct {
+	Field    string      `json:"field"`   // Dotted path into the view data, e.g. "sensors.leak"
+	Op       string      `json:"op"`      // ==, !=, >, >=, <, <=, exists
+	Value    interface{} `json:"value"`   // Compared against the field (numbers numerically)
+	Title    string      `json:"title"`   // "{{value}}" is replaced with the field value
+	Message  string      `json:"message"` // "{{value}}" is replaced with the field value
+	Priority int         `json:"priority"`
+	TTLSecs  int         `json:"ttlSeconds,omitempty"` // 0 = until the condition clears
+	View     string      `json:"view,omitempty"`
+}
+
+// defaultAlertTTL applies to API alerts without ttlSeconds
+const defaultAlertTTL = time.Hour
+
+// alertImageName is the output file name (without extension) of the alert screen
+const alertImageName = "alert"
+
+// AlertStore holds the active alerts
+type AlertStore struct {
+	mu     sync.Mutex
+	alerts map[string]Alert
+	firing map[string]bool // Data-driven rules whose condition currently holds
+	timer  *time.Timer     // Refreshes the screen when the next alert expires
+}
+
+var alertStore = &AlertStore{
+	alerts: make(map[string]Alert),
+	firing: make(map[string]bool),
+}
+
+// Raise adds or replaces an alert
+func (s *AlertStore) Raise(alert Alert) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.alerts[alert.ID] = alert
+	s.scheduleExpiryLocked()
+}
+
+// Clear removes an alert, reporting whether it existed
+func (s *AlertStore) Clear(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.alerts[id]
+	delete(s.alerts, id)
+	s.scheduleExpiryLocked()
+	return ok
+}
+
+// List returns the unexpired alerts, highest priority (then newest) first
+func (s *AlertStore) List() []Alert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneLocked()
+	list := make([]Alert, 0, len(s.alerts))
+	for _, alert := range s.alerts {
+		list = append(list, alert)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Priority != list[j].Priority {
+			return list[i].Priority > list[j].Priority
+		}
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+	return list
+}
+
+// Active returns the alert that should be on screen, if any
+func (s *AlertStore) Active() (Alert, bool) {
+	list := s.List()
+	if len(list) == 0 {
+		return Alert{}, false
+	}
+	return list[0], true
+}
+
+func (s *AlertStore) pruneLocked() {
+	now := time.Now()
+	for id, alert := range s.alerts {
+		if !alert.ExpiresAt.IsZero() && !now.Before(alert.ExpiresAt) {
+			delete(s.alerts, id)
+		}
+	}
+}
+
+// scheduleExpiryLocked arms a timer for the next expiry so the screen returns to the
+// rotation as soon as an alert runs out. Caller must hold s.mu.
+func (s *AlertStore) scheduleExpiryLocked() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+
+	var next time.Time
+	for _, alert := range s.alerts {
+		if !alert.ExpiresAt.IsZero() && (next.IsZero() || alert.ExpiresAt.Before(next)) {
+			next = alert.ExpiresAt
+		}
+	}
+	if next.IsZero() {
+		return
+	}
+
+	s.timer = time.AfterFunc(time.Until(next), func() {
+		s.mu.Lock()
+		s.pruneLocked()
+		s.scheduleExpiryLocked()
+		s.mu.Unlock()
+
+		log.Println("Alert expired, updating screen")
+		configMu.RLock()
+		defer configMu.RUnlock()
+		if err := renderCurrentViewToTRMNL(); err != nil {
+			log.Printf("Warning: Failed to update screen after alert expired: %v", err)
+		}
+	})
+}
+
+// newAlertFromRequest validates a POST /api/alerts body
+func newAlertFromRequest(req AlertRequest) (Alert, error) {
+	errors := []string{}
+	if strings.TrimSpace(req.Title) == "" {
+		errors = append(errors, "title is required")
+	}
+	if req.TTLSecs < 0 {
+		errors = append(errors, "ttlSeconds must not be negative")
+	}
+	if req.View != "" {
+		if _, ok := findView(req.View); !ok {
+			errors = append(errors, fmt.Sprintf("unknown view '%s'", req.View))
+		}
+	}
+	if len(errors) > 0 {
+		return Alert{}, fmt.Errorf("%s", strings.Join(errors, "; "))
+	}
+
+	id := req.ID
+	if id == "" {
+		suffix, err := randomHex(4)
+		if err != nil {
+			return Alert{}, err
+		}
+		id = "alert-" + suffix
+	}
+
+	ttl := defaultAlertTTL
+	if req.TTLSecs > 0 {
+		ttl = time.Duration(req.TTLSecs) * time.Second
+	}
+
+	now := time.Now()
+	return Alert{
+		ID:        id,
+		Title:     req.Title,
+		Message:   req.Message,
+		Priority:  req.Priority,
+		View:      req.View,
+		Data:      req.Data,
+		Source:    "api",
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}, nil
+}
+
+// validateAlertRules checks a view's alert rules from config.json. shown holds the names of
+// the views an alert can be rendered with.
+func validateAlertRules(rules []AlertRule, shown map[string]bool) error {
+	errors := []string{}
+	for i, rule := range rules {
+		if rule.Field == "" {
+			errors = append(errors, fmt.Sprintf("alerts[%d]: field is required", i))
+		}
+		switch rule.Op {
+		case "==", "!=", ">", ">=", "<", "<=":
+			if rule.Value == nil {
+				errors = append(errors, fmt.Sprintf("alerts[%d]: value is required for '%s'", i, rule.Op))
+			}
+		case "exists":
+		default:
+			errors = append(errors, fmt.Sprintf("alerts[%d]: unknown op '%s' (expected ==, !=, >, >=, <, <= or exists)", i, rule.Op))
+		}
+		if rule.Title == "" {
+			errors = append(errors, fmt.Sprintf("alerts[%d]: title is required", i))
+		}
+		if rule.TTLSecs < 0 {
+			errors = append(errors, fmt.Sprintf("alerts[%d]: ttlSeconds must not be negative", i))
+		}
+		if rule.View != "" && !shown[rule.View] {
+			errors = append(errors, fmt.Sprintf("alerts[%d]: unknown view '%s' (the view must be enabled or shown in a mashup)", i, rule.View))
+		}
+	}
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, "; "))
+	}
+	return nil
+}
+
+// checkViewAlerts evaluates the view's alert rules against freshly loaded data. A rule
+// raises its alert when the condition starts to hold and clears it when it stops holding.
+// It reports whether the set of alerts changed.
+func checkViewAlerts(view View, data map[string]interface{}) bool {
+	changed := false
+	for i, rule := range view.Alerts {
+		id := fmt.Sprintf("view:%s:%d", view.Name, i)
+		value, found := lookupField(data, rule.Field)
+		holds := found && compareAlertValue(value, rule.Op, rule.Value)
+
+		alertStore.mu.Lock()
+		wasFiring := alertStore.firing[id]
+		alertStore.firing[id] = holds
+		alertStore.mu.Unlock()
+
+		switch {
+		case holds && !wasFiring:
+			now := time.Now()
+			alert := Alert{
+				ID:        id,
+				Title:     strings.ReplaceAll(rule.Title, "{{value}}", fmt.Sprint(value)),
+				Message:   strings.ReplaceAll(rule.Message, "{{value}}", fmt.Sprint(value)),
+				Priority:  rule.Priority,
+				View:      rule.View,
+				Source:    "view:" + view.Name,
+				CreatedAt: now,
+			}
+			if rule.TTLSecs > 0 {
+				alert.ExpiresAt = now.Add(time.Duration(rule.TTLSecs) * time.Second)
+			}
+			log.Printf("Alert raised by view %s: %s", view.Name, alert.Title)
+			alertStore.Raise(alert)
+			changed = true
+		case !holds && wasFiring:
+			if alertStore.Clear(id) {
+				log.Printf("Alert cleared by view %s: %s", view.Name, rule.Title)
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// showAlertChange pushes an alert raised or cleared by a view's data to screen.bmp right
+// away, like POST /api/alerts, instead of on the next rotation check
+func showAlertChange(view View) {
+	if err := renderCurrentViewToTRMNL(); err != nil {
+		log.Printf("Warning: Failed to update screen after alert change by view %s: %v", view.Name, err)
+	}
+}
+
+// lookupField resolves a dotted path like "sensors.leak" or "items.0.state"
+func lookupField(data map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = data
+	for _, key := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func compareAlertValue(actual interface{}, op string, expected interface{}) bool {
+	if op == "exists" {
+		return actual != nil
+	}
+
+	a, aNum := toFloat(actual)
+	b, bNum := toFloat(expected)
+	if aNum && bNum {
+		switch op {
+		case "==":
+			return a == b
+		case "!=":
+			return a != b
+		case ">":
+			return a > b
+		case ">=":
+			return a >= b
+		case "<":
+			return a < b
+		case "<=":
+			return a <= b
+		}
+		return false
+	}
+
+	as, bs := fmt.Sprint(actual), fmt.Sprint(expected)
+	switch op {
+	case "==":
+		return as == bs
+	case "!=":
+		return as != bs
+	case ">":
+		return as > bs
+	case ">=":
+		return as >= bs
+	case "<":
+		return as < bs
+	case "<=":
+		return as <= bs
+	}
+	return false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// renderAlertToTRMNL renders the alert screen for every output profile and pushes it to
+// screen.bmp
+func renderAlertToTRMNL(alert Alert) error {
+	for _, profile := range activeProfiles() {
+		if err := renderAlertImage(alert, profile); err != nil {
+			log.Printf("Warning: %v (profile %s)", err, profile.Name)
+		}
+	}
+
+	html, dither, err := renderAlertHTML(alert, defaultProfile())
+	if err != nil {
+		return err
+	}
+
+	pngPath := filepath.Join(config.Paths.OutputDir, alertImageName+".png")
+	if err := renderToImage(html, pngPath, dither); err != nil {
+		return fmt.Errorf("failed to render alert: %w", err)
+	}
+	if err := convertPNGToBMP(pngPath, filepath.Join(config.Paths.OutputDir, alertImageName+".bmp"), 1); err != nil {
+		return fmt.Errorf("failed to write alert BMP: %w", err)
+	}
+	if filepath.Ext(config.Render.OutputPath) == ".bmp" {
+		if err := convertPNGToBMP(pngPath, config.Render.OutputPath, 1); err != nil {
+			return fmt.Errorf("failed to write %s: %w", config.Render.OutputPath, err)
+		}
+	} else if err := renderToImage(html, config.Render.OutputPath, dither); err != nil {
+		return fmt.Errorf("failed to render alert: %w", err)
+	}
+
+	log.Printf("Showing alert '%s' (priority %d) on %s", alert.Title, alert.Priority, config.Render.OutputPath)
+	return nil
+}
+
+// renderAlertImage writes output/alert@<profile>.png and .bmp for devices with that profile
+func renderAlertImage(alert Alert, profile OutputProfile) error {
+	html, dither, err := renderAlertHTML(alert, profile)
+	if err != nil {
+		return err
+	}
+	pngPath := filepath.Join(config.Paths.OutputDir, profile.imageName(alertImageName)+".png")
+	if err := renderProfileImage(html, pngPath, dither, profile); err != nil {
+		return fmt.Errorf("failed to render alert: %w", err)
+	}
+	bmpPath := filepath.Join(config.Paths.OutputDir, profile.imageName(alertImageName)+".bmp")
+	if err := convertPNGToBMP(pngPath, bmpPath, profile.BitDepth); err != nil {
+		return fmt.Errorf("failed to write alert BMP: %w", err)
+	}
+	return nil
+}
+
+// renderAlertHTML renders the alert with its configured view, or the built-in alert screen,
+// at the profile's layout size
+func renderAlertHTML(alert Alert, profile OutputProfile) (string, DitherSettings, error) {
+	if view, ok := findView(alert.View); ok && view.Mashup == nil {
+		viewData, err := loadViewData(view)
+		if err != nil {
+			return "", view.Dither, fmt.Errorf("failed to load data for alert view '%s': %w", view.Name, err)
+		}
+		viewData.Alert = &alert
+		viewData.setSize(profile.layoutSize())
+		html, err := executeViewTemplate(view, viewData)
+		return html, view.Dither, err
+	}
+
+	tmpl, err := template.New(alertImageName).Parse(alertTemplate)
+	if err != nil {
+		return "", DitherSettings{}, err
+	}
+	viewData := &ViewData{
+		Title:     alert.Title,
+		Timestamp: time.Now().Format("2006-01-02 15:04:05"),
+		Fields:    alert.Data,
+		Alert:     &alert,
+	}
+	viewData.setSize(profile.layoutSize())
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, viewData); err != nil {
+		return "", DitherSettings{}, fmt.Errorf("failed to execute alert template: %w", err)
+	}
+	return buf.String(), DitherSettings{}, nil
+}
+
+// alertImageFile returns the alert screen for devices with their own playlist or output
+// profile, if an alert is active
+func alertImageFile(profile OutputProfile, ext string) (string, bool) {
+	if _, ok := alertStore.Active(); !ok {
+		return "", false
+	}
+	path := filepath.Join(config.Paths.OutputDir, profile.imageName(alertImageName)+ext)
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// alertTemplate is the built-in alert screen
+const alertTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <meta name="viewport" content="width={{.Width}}, height={{.Height}}, initial-scale=1.0">
+  <title>Alert</title>
+  <style>
+{{.Styles}}
+    .alert-box {
+      grid-column: span 2;
+      display: flex;
+      flex-direction: column;
+      justify-content: center;
+      align-items: center;
+      border: 6px solid #000000;
+      padding: 20px;
+      text-align: center;
+    }
+    .alert-title {
+      font-size: 48px;
+      font-weight: bold;
+      margin-bottom: 16px;
+    }
+    .alert-message {
+      font-size: 28px;
+    }
+  </style>
+</head>
+<body>
+  <div class="header">
+    <div class="header-title">ALERT</div>
+    <div class="header-timestamp">{{.Timestamp}}</div>
+  </div>
+  <div class="content">
+    <div class="alert-box">
+      <div class="alert-title">{{.Alert.Title}}</div>
+      {{if .Alert.Message}}<div class="alert-message">{{.Alert.Message}}</div>{{end}}
+    </div>
+  </div>
+</body>
+</html>`
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+)
+
+// BMP3 layout constants for a 1-bit-per-pixel, 2-color palette image
+const (
+	bmpFileHeaderSize = 14
+	bmpInfoHeaderSize = 40 // BITMAPINFOHEADER (BMP version 3)
+	bmpPaletteSize    = 2 * 4
+	bmpPixelOffset    = bmpFileHeaderSize + bmpInfoHeaderSize + bmpPaletteSize
+	bmpPixelsPerMeter = 2835 // 72 DPI, same value ImageMagick writes
+)
+
+// encodeBMP1 writes img as an uncompressed 1bpp BMP3 file, the format stock
+// TRMNL firmware expects. Palette index 0 is black and index 1 is white, rows
+// are stored bottom-up and padded to a 4-byte boundary. Pixels with a
+// luminance of 128 or more become white, everything else black.
+func encodeBMP1(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("cannot encode empty image (%dx%d) as BMP", width, height)
+	}
+
+	rowSize := ((width + 31) / 32) * 4
+	imageSize := rowSize * height
+	fileSize := bmpPixelOffset + imageSize
+
+	header := make([]byte, bmpPixelOffset)
+
+	// BITMAPFILEHEADER
+	header[0], header[1] = 'B', 'M'
+	binary.LittleEndian.PutUint32(header[2:], uint32(fileSize))
+	binary.LittleEndian.PutUint32(header[10:], uint32(bmpPixelOffset))
+
+	// BITMAPINFOHEADER - positive height means bottom-up row order
+	info := header[bmpFileHeaderSize:]
+	binary.LittleEndian.PutUint32(info[0:], bmpInfoHeaderSize)
+	binary.LittleEndian.PutUint32(info[4:], uint32(int32(width)))
+	binary.LittleEndian.PutUint32(info[8:], uint32(int32(height)))
+	binary.LittleEndian.PutUint16(info[12:], 1) // planes
+	binary.LittleEndian.PutUint16(info[14:], 1) // bits per pixel
+	binary.LittleEndian.PutUint32(info[16:], 0) // BI_RGB (uncompressed)
+	binary.LittleEndian.PutUint32(info[20:], uint32(imageSize))
+	binary.LittleEndian.PutUint32(info[24:], bmpPixelsPerMeter)
+	binary.LittleEndian.PutUint32(info[28:], bmpPixelsPerMeter)
+	binary.LittleEndian.PutUint32(info[32:], 2) // colors used
+	binary.LittleEndian.PutUint32(info[36:], 2) // important colors
+
+	// Palette entries are stored as B, G, R, reserved
+	palette := header[bmpFileHeaderSize+bmpInfoHeaderSize:]
+	copy(palette[0:4], []byte{0x00, 0x00, 0x00, 0x00}) // index 0: black
+	copy(palette[4:8], []byte{0xFF, 0xFF, 0xFF, 0x00}) // index 1: white
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	row := make([]byte, rowSize)
+	for y := bounds.Max.Y - 1; y >= bounds.Min.Y; y-- {
+		for i := range row {
+			row[i] = 0
+		}
+		for x := 0; x < width; x++ {
+			gray := color.GrayModel.Convert(img.At(bounds.Min.X+x, y)).(color.Gray)
+			if gray.Y >= 128 {
+				row[x/8] |= 0x80 >> uint(x%8)
+			}
+		}
+		if _, err := w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeBMP4 writes img as an uncompressed 4bpp BMP3 file with a 16-level gray palette
+// (index 0 black, 15 white), used for grayscale output profiles. 2-bit images use every
+// fifth palette entry.
+func encodeBMP4(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("cannot encode empty image (%dx%d) as BMP", width, height)
+	}
+
+	const colors = 16
+	pixelOffset := bmpFileHeaderSize + bmpInfoHeaderSize + colors*4
+	rowSize := ((width*4 + 31) / 32) * 4
+	imageSize := rowSize * height
+
+	header := make([]byte, pixelOffset)
+	header[0], header[1] = 'B', 'M'
+	binary.LittleEndian.PutUint32(header[2:], uint32(pixelOffset+imageSize))
+	binary.LittleEndian.PutUint32(header[10:], uint32(pixelOffset))
+
+	info := header[bmpFileHeaderSize:]
+	binary.LittleEndian.PutUint32(info[0:], bmpInfoHeaderSize)
+	binary.LittleEndian.PutUint32(info[4:], uint32(int32(width)))
+	binary.LittleEndian.PutUint32(info[8:], uint32(int32(height)))
+	binary.LittleEndian.PutUint16(info[12:], 1) // planes
+	binary.LittleEndian.PutUint16(info[14:], 4) // bits per pixel
+	binary.LittleEndian.PutUint32(info[16:], 0) // BI_RGB (uncompressed)
+	binary.LittleEndian.PutUint32(info[20:], uint32(imageSize))
+	binary.LittleEndian.PutUint32(info[24:], bmpPixelsPerMeter)
+	binary.LittleEndian.PutUint32(info[28:], bmpPixelsPerMeter)
+	binary.LittleEndian.PutUint32(info[32:], colors)
+	binary.LittleEndian.PutUint32(info[36:], colors)
+
+	palette := header[bmpFileHeaderSize+bmpInfoHeaderSize:]
+	for i := 0; i < colors; i++ {
+		v := byte(i * 17)
+		copy(palette[i*4:], []byte{v, v, v, 0x00})
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	row := make([]byte, rowSize)
+	for y := bounds.Max.Y - 1; y >= bounds.Min.Y; y-- {
+		for i := range row {
+			row[i] = 0
+		}
+		for x := 0; x < width; x++ {
+			gray := color.GrayModel.Convert(img.At(bounds.Min.X+x, y)).(color.Gray)
+			index := (int(gray.Y) + 8) / 17
+			if x%2 == 0 {
+				row[x/2] |= byte(index) << 4
+			} else {
+				row[x/2] |= byte(index)
+			}
+		}
+		if _, err := w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeBMP writes a 1-bit BMP, or a 4bpp grayscale BMP for a bitDepth of 2 or 4
+func encodeBMP(w io.Writer, img image.Image, bitDepth int) error {
+	if bitDepth > 1 {
+		return encodeBMP4(w, img)
+	}
+	return encodeBMP1(w, img)
+}
+
+// convertPNGToBMP re-encodes an already dithered PNG as BMP3 (see encodeBMP) with atomic
+// replacement
+func convertPNGToBMP(pngPath, bmpPath string, bitDepth int) error {
+	file, err := os.Open(pngPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", pngPath, err)
+	}
+
+	tempPath := bmpPath + ".tmp"
+	outputFile, err := os.Create(tempPath)
+	if err != nil {
+		return err
+	}
+	if err := encodeBMP(outputFile, img, bitDepth); err != nil {
+		outputFile.Close()
+		os.Remove(tempPath)
+		return err
+	}
+	outputFile.Close()
+
+	if err := os.Rename(tempPath, bmpPath); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to atomically replace %s: %w", bmpPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// grayImage builds an image from rows of gray values, top row first
+func grayImage(rows [][]uint8) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, len(rows[0]), len(rows)))
+	for y, row := range rows {
+		for x, v := range row {
+			img.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+	return img
+}
+
+func TestEncodeBMP1Golden(t *testing.T) {
+	// 5x3: the odd width leaves 27 bits of padding per row
+	img := grayImage([][]uint8{
+		{255, 0, 255, 0, 255}, // top
+		{0, 0, 0, 0, 127},     // 127 is below the cutoff
+		{255, 128, 255, 200, 255},
+	})
+
+	want := []byte{
+		// BITMAPFILEHEADER
+		'B', 'M',
+		0x4A, 0x00, 0x00, 0x00, // file size: 62 + 3 rows * 4 bytes
+		0x00, 0x00, 0x00, 0x00, // reserved
+		0x3E, 0x00, 0x00, 0x00, // pixel data offset: 62
+		// BITMAPINFOHEADER
+		0x28, 0x00, 0x00, 0x00, // header size: 40
+		0x05, 0x00, 0x00, 0x00, // width
+		0x03, 0x00, 0x00, 0x00, // height, positive = bottom-up
+		0x01, 0x00, // planes
+		0x01, 0x00, // bits per pixel
+		0x00, 0x00, 0x00, 0x00, // BI_RGB
+		0x0C, 0x00, 0x00, 0x00, // image size
+		0x13, 0x0B, 0x00, 0x00, // 2835 pixels per meter
+		0x13, 0x0B, 0x00, 0x00,
+		0x02, 0x00, 0x00, 0x00, // colors used
+		0x02, 0x00, 0x00, 0x00, // important colors
+		// Palette (B, G, R, reserved)
+		0x00, 0x00, 0x00, 0x00, // 0: black
+		0xFF, 0xFF, 0xFF, 0x00, // 1: white
+		// Pixels, bottom row first, each row padded to 4 bytes
+		0xF8, 0x00, 0x00, 0x00, // 11111
+		0x00, 0x00, 0x00, 0x00, // 00000
+		0xA8, 0x00, 0x00, 0x00, // 10101
+	}
+
+	var buf bytes.Buffer
+	if err := encodeBMP1(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("encodeBMP1 output mismatch\n got: % X\nwant: % X", buf.Bytes(), want)
+	}
+}
+
+func TestEncodeBMP1SubImage(t *testing.T) {
+	// Bounds that don't start at 0,0 encode the same as a copy
+	full := grayImage([][]uint8{
+		{0, 0, 0, 0},
+		{0, 255, 0, 0},
+		{0, 0, 255, 0},
+	})
+	sub := full.SubImage(image.Rect(1, 1, 4, 3))
+	copied := grayImage([][]uint8{
+		{255, 0, 0},
+		{0, 255, 0},
+	})
+
+	var a, b bytes.Buffer
+	if err := encodeBMP1(&a, sub); err != nil {
+		t.Fatal(err)
+	}
+	if err := encodeBMP1(&b, copied); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Errorf("sub-image output mismatch\n got: % X\nwant: % X", a.Bytes(), b.Bytes())
+	}
+}
+
+func TestEncodeBMP1Empty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := encodeBMP1(&buf, image.NewGray(image.Rect(0, 0, 0, 4))); err == nil {
+		t.Error("expected an error for an empty image")
+	}
+}
+
+func TestEncodeBMP4Golden(t *testing.T) {
+	// 3x2: 12 bits of pixels per row, padded to 4 bytes
+	img := grayImage([][]uint8{
+		{0, 136, 255}, // top: indexes 0, 8, 15
+		{17, 34, 238}, // indexes 1, 2, 14
+	})
+
+	want := []byte{
+		'B', 'M',
+		0x7E, 0x00, 0x00, 0x00, // file size: 118 + 2 rows * 4 bytes
+		0x00, 0x00, 0x00, 0x00,
+		0x76, 0x00, 0x00, 0x00, // pixel data offset: 118
+		0x28, 0x00, 0x00, 0x00,
+		0x03, 0x00, 0x00, 0x00, // width
+		0x02, 0x00, 0x00, 0x00, // height
+		0x01, 0x00,
+		0x04, 0x00, // bits per pixel
+		0x00, 0x00, 0x00, 0x00,
+		0x08, 0x00, 0x00, 0x00, // image size
+		0x13, 0x0B, 0x00, 0x00,
+		0x13, 0x0B, 0x00, 0x00,
+		0x10, 0x00, 0x00, 0x00, // colors used: 16
+		0x10, 0x00, 0x00, 0x00,
+		// 16 grays, 17 apart
+		0x00, 0x00, 0x00, 0x00, 0x11, 0x11, 0x11, 0x00, 0x22, 0x22, 0x22, 0x00, 0x33, 0x33, 0x33, 0x00,
+		0x44, 0x44, 0x44, 0x00, 0x55, 0x55, 0x55, 0x00, 0x66, 0x66, 0x66, 0x00, 0x77, 0x77, 0x77, 0x00,
+		0x88, 0x88, 0x88, 0x00, 0x99, 0x99, 0x99, 0x00, 0xAA, 0xAA, 0xAA, 0x00, 0xBB, 0xBB, 0xBB, 0x00,
+		0xCC, 0xCC, 0xCC, 0x00, 0xDD, 0xDD, 0xDD, 0x00, 0xEE, 0xEE, 0xEE, 0x00, 0xFF, 0xFF, 0xFF, 0x00,
+		// Pixels, bottom row first, high nibble = left pixel
+		0x12, 0xE0, 0x00, 0x00,
+		0x08, 0xF0, 0x00, 0x00,
+	}
+
+	var buf bytes.Buffer
+	if err := encodeBMP(&buf, img, 4); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("encodeBMP4 output mismatch\n got: % X\nwant: % X", buf.Bytes(), want)
+	}
+}
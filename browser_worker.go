@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BrowserWorker drives a long-lived Node/Playwright process (scripts/playwright-worker.js)
+// over a line-delimited JSON protocol on stdin/stdout, so Chromium starts once instead of
+// once per render. The process is restarted on the next render if it exits or hangs.
+type BrowserWorker struct {
+	scriptPath string
+	pages      chan struct{} // Semaphore capping concurrent pages
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	nextID  int
+	pending map[int]chan workerResponse
+	stderr  *workerLog
+}
+
+type workerRequest struct {
+	ID     int    `json:"id"`
+	HTML   string `json:"html"`
+	Output string `json:"output"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+type workerResponse struct {
+	ID    int    `json:"id"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// browserRenderTimeout bounds a single render, including browser startup on a cold worker
+const browserRenderTimeout = 90 * time.Second
+
+// defaultMaxConcurrentPages is used when render.maxConcurrentPages is not set
+const defaultMaxConcurrentPages = 2
+
+var browserWorker *BrowserWorker
+var browserWorkerOnce sync.Once
+
+// getBrowserWorker returns the shared worker, created on first use
+func getBrowserWorker() *BrowserWorker {
+	browserWorkerOnce.Do(func() {
+		maxPages := config.Render.MaxConcurrentPages
+		if maxPages <= 0 {
+			maxPages = defaultMaxConcurrentPages
+		}
+		browserWorker = newBrowserWorker(filepath.Join(".", "scripts", "playwright-worker.js"), maxPages)
+	})
+	return browserWorker
+}
+
+func newBrowserWorker(scriptPath string, maxPages int) *BrowserWorker {
+	return &BrowserWorker{
+		scriptPath: scriptPath,
+		pages:      make(chan struct{}, maxPages),
+		pending:    make(map[int]chan workerResponse),
+	}
+}
+
+// Render renders html to a PNG screenshot at outputPath
+func (w *BrowserWorker) Render(html, outputPath string, width, height int) error {
+	w.pages <- struct{}{}
+	defer func() { <-w.pages }()
+
+	w.mu.Lock()
+	if err := w.startLocked(); err != nil {
+		w.mu.Unlock()
+		return err
+	}
+	w.nextID++
+	req := workerRequest{ID: w.nextID, HTML: html, Output: outputPath, Width: width, Height: height}
+	respCh := make(chan workerResponse, 1)
+	w.pending[req.ID] = respCh
+	cmd := w.cmd
+
+	line, err := json.Marshal(req)
+	if err == nil {
+		_, err = w.stdin.Write(append(line, '\n'))
+	}
+	if err != nil {
+		delete(w.pending, req.ID)
+		w.mu.Unlock()
+		w.kill(cmd, "write failed")
+		return fmt.Errorf("failed to send render request to browser worker: %w", err)
+	}
+	w.mu.Unlock()
+
+	select {
+	case resp := <-respCh:
+		if !resp.OK {
+			return fmt.Errorf("playwright render failed: %s", resp.Error)
+		}
+		return nil
+	case <-time.After(browserRenderTimeout):
+		w.mu.Lock()
+		delete(w.pending, req.ID)
+		w.mu.Unlock()
+		w.kill(cmd, "render timed out")
+		return fmt.Errorf("playwright render timed out after %v", browserRenderTimeout)
+	}
+}
+
+// startLocked launches the worker process if it isn't running. Caller must hold w.mu.
+func (w *BrowserWorker) startLocked() error {
+	if w.cmd != nil {
+		return nil
+	}
+
+	if _, err := os.Stat(w.scriptPath); err != nil {
+		return fmt.Errorf("playwright worker script not found at %s: %w. Make sure the script exists and Node.js is installed", w.scriptPath, err)
+	}
+
+	cmd := exec.Command("node", w.scriptPath)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	w.stderr = &workerLog{}
+	cmd.Stderr = w.stderr
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start browser worker: %w. Make sure Node.js and Playwright are installed (npm install playwright)", err)
+	}
+
+	log.Printf("Started browser worker (pid %d)", cmd.Process.Pid)
+	w.cmd = cmd
+	w.stdin = stdin
+	go w.readResponses(cmd, stdout)
+	return nil
+}
+
+// readResponses dispatches worker output until the process exits, then fails anything
+// still pending so the next Render starts a fresh worker
+func (w *BrowserWorker) readResponses(cmd *exec.Cmd, stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var resp workerResponse
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			log.Printf("Browser worker: unexpected output: %s", scanner.Text())
+			continue
+		}
+		w.mu.Lock()
+		if ch, ok := w.pending[resp.ID]; ok {
+			delete(w.pending, resp.ID)
+			ch <- resp
+		}
+		w.mu.Unlock()
+	}
+
+	err := cmd.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	reason := "browser worker exited"
+	if err != nil {
+		reason += " (" + err.Error() + ")"
+	}
+	if msg := w.stderr.String(); msg != "" {
+		reason += ": " + msg
+	}
+	if w.cmd == cmd {
+		log.Printf("Warning: %s (will restart on next render)", reason)
+		w.cmd = nil
+		w.stdin = nil
+	}
+	for id, ch := range w.pending {
+		delete(w.pending, id)
+		ch <- workerResponse{ID: id, Error: reason}
+	}
+}
+
+// kill terminates a hung or broken worker process; readResponses handles the cleanup
+func (w *BrowserWorker) kill(cmd *exec.Cmd, reason string) {
+	if cmd == nil || cmd.Process == nil {
+		return
+	}
+	log.Printf("Warning: Restarting browser worker: %s", reason)
+	cmd.Process.Kill()
+}
+
+// Close shuts the worker down gracefully by closing its stdin
+func (w *BrowserWorker) Close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.stdin != nil {
+		w.stdin.Close()
+	}
+}
+
+// workerLog keeps the tail of the worker's stderr for error messages
+type workerLog struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (l *workerLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.buf = append(l.buf, p...)
+	if len(l.buf) > 4096 {
+		l.buf = l.buf[len(l.buf)-4096:]
+	}
+	return len(p), nil
+}
+
+func (l *workerLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return strings.TrimSpace(string(l.buf))
+}
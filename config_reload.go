@@ -0,0 +1,381 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const configPath = "config.json"
+
+// configWatchInterval is how often config.json and the template/data directories are polled
+const configWatchInterval = 2 * time.Second
+
+// configMu guards config, views and the rotation state against a concurrent reload. HTTP
+// handlers, the scheduler and the rotation loop hold the read lock while they work; applying
+// a reload takes the write lock, so nobody sees a half-applied config.
+var configMu sync.RWMutex
+
+// reloadMu serializes reloads from the watcher and /api/config/reload
+var reloadMu sync.Mutex
+
+// renderTicker drives startScheduler; a reload resets it when the refresh interval changes
+var renderTicker *time.Ticker
+
+// ConfigReloadStatus describes the most recent reload attempt (exposed in /api/status)
+type ConfigReloadStatus struct {
+	LastAttempt time.Time `json:"lastAttempt"`
+	LastReload  time.Time `json:"lastReload,omitzero"` // Last successful reload
+	Error       string    `json:"error,omitempty"`     // Why the last attempt was rejected
+}
+
+var configReloadStatus ConfigReloadStatus
+
+// loadConfig reads and validates a config file, returning the config and the views it
+// defines. Nothing global is modified, so a bad file can be rejected safely.
+func loadConfig(path string) (Config, []View, error) {
+	var cfg Config
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	if err := validateDitherSettings(cfg.Render.DitherSettings); err != nil {
+		return cfg, nil, fmt.Errorf("invalid render settings: %w", err)
+	}
+	if err := validateRendererName(cfg.Render.Renderer); err != nil {
+		return cfg, nil, fmt.Errorf("invalid render settings: %w", err)
+	}
+	if err := validateProfiles(cfg.Render.Profiles); err != nil {
+		return cfg, nil, fmt.Errorf("invalid render.profiles: %w", err)
+	}
+	if cfg.Render.RefreshIntervalMins <= 0 {
+		return cfg, nil, fmt.Errorf("invalid render settings: refreshIntervalMinutes must be positive")
+	}
+	if err := validateScriptSources(cfg.DataSources.Scripts); err != nil {
+		return cfg, nil, fmt.Errorf("invalid data sources: %w", err)
+	}
+	if err := validateRefreshRules(cfg.TRMNL.RefreshRules); err != nil {
+		return cfg, nil, fmt.Errorf("invalid trmnl.refreshRules: %w", err)
+	}
+	if err := validateFirmwareSettings(cfg); err != nil {
+		return cfg, nil, fmt.Errorf("invalid firmware settings: %w", err)
+	}
+	if err := validateDeviceLogSettings(cfg); err != nil {
+		return cfg, nil, fmt.Errorf("invalid deviceLogs settings: %w", err)
+	}
+
+	if cfg.Paths.DevicesFile == "" {
+		cfg.Paths.DevicesFile = "./devices.json"
+	}
+
+	loadedViews, err := viewsFromConfig(cfg)
+	if err != nil {
+		return cfg, nil, err
+	}
+
+	return cfg, loadedViews, nil
+}
+
+// reloadConfig re-reads config.json and applies it atomically, then re-renders. An invalid
+// config is rejected and logged, and the last good config stays active.
+func reloadConfig() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	newConfig, newViews, err := loadConfig(configPath)
+	if err == nil {
+		err = applyConfig(newConfig, newViews)
+	}
+
+	configMu.Lock()
+	configReloadStatus.LastAttempt = time.Now()
+	if err != nil {
+		configReloadStatus.Error = err.Error()
+	} else {
+		configReloadStatus.Error = ""
+		configReloadStatus.LastReload = configReloadStatus.LastAttempt
+	}
+	configMu.Unlock()
+
+	if err != nil {
+		log.Printf("Warning: Rejected config reload, keeping last good config: %v", err)
+		return err
+	}
+
+	log.Printf("Config reloaded: %d view(s)", len(newViews))
+
+	configMu.RLock()
+	defer configMu.RUnlock()
+	if err := renderAllViews(); err != nil {
+		log.Printf("Render after config reload failed: %v", err)
+	}
+	return nil
+}
+
+// applyConfig swaps in a validated config. Resources that can fail (the output directory, a
+// new device registry, a new listen address) are prepared first so a failure leaves the
+// running config untouched.
+func applyConfig(newConfig Config, newViews []View) error {
+	configMu.RLock()
+	oldConfig := config
+	configMu.RUnlock()
+
+	if err := os.MkdirAll(newConfig.Paths.OutputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	registry := deviceRegistry
+	if newConfig.Paths.DevicesFile != oldConfig.Paths.DevicesFile {
+		loaded, err := loadDeviceRegistry(newConfig.Paths.DevicesFile)
+		if err != nil {
+			return err
+		}
+		registry = loaded
+	}
+
+	var listener net.Listener
+	newAddr := fmt.Sprintf("%s:%s", newConfig.Server.Host, newConfig.Server.Port)
+	if httpServer != nil && newAddr != httpServer.Addr {
+		ln, err := net.Listen("tcp", newAddr)
+		if err != nil {
+			return fmt.Errorf("cannot listen on %s: %w", newAddr, err)
+		}
+		listener = ln
+	}
+
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	currentName := getCurrentView().Name
+	config = newConfig
+	views = newViews
+	deviceRegistry = registry
+
+	// Stay on the same view if it still exists
+	currentViewIndex = 0
+	for i, view := range views {
+		if view.Name == currentName {
+			currentViewIndex = i
+			break
+		}
+	}
+
+	if newConfig.Render.Renderer != oldConfig.Render.Renderer {
+		setRenderer(nil) // Re-selected on the next render
+	}
+	if newConfig.Render.RefreshIntervalMins != oldConfig.Render.RefreshIntervalMins && renderTicker != nil {
+		renderTicker.Reset(time.Duration(newConfig.Render.RefreshIntervalMins) * time.Minute)
+		log.Printf("Render interval changed to %d minute(s)", newConfig.Render.RefreshIntervalMins)
+	}
+	if newConfig.Render.MaxConcurrentPages != oldConfig.Render.MaxConcurrentPages {
+		log.Printf("Warning: render.maxConcurrentPages takes effect after a restart")
+	}
+	if listener != nil {
+		restartHTTPServer(listener)
+	}
+
+	return nil
+}
+
+// restartHTTPServer moves the server to a new listener and gracefully shuts down the old
+// one (in-flight requests, including the one that triggered the reload, are finished)
+func restartHTTPServer(listener net.Listener) {
+	old := httpServer
+	server := &http.Server{
+		Addr:    fmt.Sprintf("%s:%s", config.Server.Host, config.Server.Port),
+		Handler: old.Handler,
+	}
+	httpServer = server
+
+	log.Printf("Server now listening on http://%s", server.Addr)
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("Warning: Server on %s stopped: %v", server.Addr, err)
+		}
+	}()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := old.Shutdown(ctx); err != nil {
+			log.Printf("Warning: Old server shutdown error: %v", err)
+		}
+	}()
+}
+
+// unlockedPaths are handlers that must not hold the config read lock (they take the
+// write lock themselves, or stay open indefinitely)
+var unlockedPaths = map[string]bool{
+	"/api/config/reload":  true,
+	"/api/preview/events": true,
+}
+
+// withConfigReadLock holds the config read lock for the duration of each request
+func withConfigReadLock(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if unlockedPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		configMu.RLock()
+		defer configMu.RUnlock()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// startConfigWatcher polls config.json and the template/data directories. A changed
+// config.json triggers a full reload; changed templates or data only trigger a re-render.
+// Changes are applied once the files have stopped changing for one poll interval.
+func startConfigWatcher() {
+	ticker := time.NewTicker(configWatchInterval)
+	defer ticker.Stop()
+
+	last := watchSnapshot()
+	pending := false
+	configChanged := false
+
+	for range ticker.C {
+		current := watchSnapshot()
+		if !pending && onlySelfWrites(current, last) {
+			last = current
+			continue
+		}
+		if !sameSnapshot(current, last) {
+			if current[configPath] != last[configPath] {
+				configChanged = true
+			}
+			last = current
+			pending = true
+			continue
+		}
+		if !pending {
+			continue
+		}
+
+		if configChanged {
+			log.Printf("%s changed, reloading...", configPath)
+			reloadConfig()
+		} else {
+			log.Println("Templates or data changed, re-rendering...")
+			configMu.RLock()
+			if err := renderAllViews(); err != nil {
+				log.Printf("Render after file change failed: %v", err)
+			}
+			configMu.RUnlock()
+		}
+		pending = false
+		configChanged = false
+		last = watchSnapshot()
+	}
+}
+
+// watchedExtensions limits directory watching to template and data files
+var watchedExtensions = map[string]bool{
+	".html": true, ".htm": true, ".json": true, ".css": true, ".liquid": true, ".tmpl": true,
+}
+
+// watchSnapshot returns the modification time and size of every watched file
+func watchSnapshot() map[string]string {
+	configMu.RLock()
+	dirs := watchedDirs()
+	outputDir, _ := filepath.Abs(config.Paths.OutputDir)
+	devicesFile, _ := filepath.Abs(config.Paths.DevicesFile)
+	configMu.RUnlock()
+
+	snapshot := make(map[string]string)
+	if info, err := os.Stat(configPath); err == nil {
+		snapshot[configPath] = fileFingerprint(info)
+	}
+
+	for _, dir := range dirs {
+		if abs, _ := filepath.Abs(dir); abs == outputDir {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !watchedExtensions[strings.ToLower(filepath.Ext(entry.Name()))] {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if abs, _ := filepath.Abs(path); abs == devicesFile || filepath.Base(path) == configPath {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			snapshot[path] = fileFingerprint(info)
+		}
+	}
+	return snapshot
+}
+
+// fileFingerprint identifies a version of a file by modification time and size
+func fileFingerprint(info os.FileInfo) string {
+	return fmt.Sprintf("%d/%d", info.ModTime().UnixNano(), info.Size())
+}
+
+// watchedDirs lists the directories holding templates and data files. Caller must hold configMu.
+func watchedDirs() []string {
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if path != "" {
+			seen[filepath.Clean(filepath.Dir(path))] = true
+		}
+	}
+
+	watch := func(view View) {
+		add(view.Template)
+		for _, source := range view.dataSources() {
+			if source.Type == sourceFile {
+				add(source.Path)
+			}
+		}
+	}
+	for _, view := range views {
+		watch(view)
+		if view.Mashup != nil {
+			for _, child := range view.Mashup.Children {
+				watch(child)
+			}
+		}
+	}
+	for _, path := range config.DataSources.JSONFiles {
+		add(path)
+	}
+
+	dirs := make([]string, 0, len(seen))
+	for dir := range seen {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+func sameSnapshot(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Data source types for views[].sources
+const (
+	sourceFile    = "file"
+	sourceHTTP    = "http"
+	sourceScript  = "script"
+	sourceStatic  = "static"
+	sourceWebhook = "webhook" // Data pushed to POST /api/views/<name>/data
+)
+
+// Merge strategies for combining a source into the view data
+const (
+	mergeReplace  = "replace"  // Top-level keys overwrite earlier values (default)
+	mergeDeep     = "deep"     // Nested objects are merged recursively
+	mergeAppend   = "append"   // Like deep, but arrays are concatenated
+	mergeDefaults = "defaults" // Only keys that aren't set yet are added
+)
+
+// defaultHTTPSourceTimeout bounds HTTP sources that don't set timeoutSeconds
+const defaultHTTPSourceTimeout = 10 * time.Second
+
+// DataSource is one entry of a view's "sources" pipeline in config.json
+type DataSource struct {
+	Type        string            `json:"type"`                     // file, http, script, static or webhook
+	Path        string            `json:"path,omitempty"`           // file: path to a JSON file
+	URL         string            `json:"url,omitempty"`            // http: endpoint returning JSON
+	Headers     map[string]string `json:"headers,omitempty"`        // http: extra request headers
+	TimeoutSecs int               `json:"timeoutSeconds,omitempty"` // http: request timeout
+	Script      *ScriptSource     `json:"script,omitempty"`         // script: command string or object
+	Value       interface{}       `json:"value,omitempty"`          // static: literal value
+	Merge       string            `json:"merge,omitempty"`          // replace, deep, append or defaults
+	Namespace   string            `json:"namespace,omitempty"`      // Store the result under this key
+}
+
+func (s DataSource) describe() string {
+	switch s.Type {
+	case sourceFile:
+		return "file " + s.Path
+	case sourceHTTP:
+		return "http " + s.URL
+	case sourceScript:
+		if s.Script != nil {
+			return "script " + s.Script.displayName()
+		}
+	}
+	return s.Type
+}
+
+// validateDataSource checks a single source entry from config.json
+func validateDataSource(s DataSource) error {
+	errors := []string{}
+
+	switch s.Type {
+	case sourceFile:
+		if s.Path == "" {
+			errors = append(errors, "path is required")
+		}
+	case sourceHTTP:
+		if !strings.HasPrefix(s.URL, "http://") && !strings.HasPrefix(s.URL, "https://") {
+			errors = append(errors, "url must start with http:// or https://")
+		}
+		if s.TimeoutSecs < 0 {
+			errors = append(errors, "timeoutSeconds must not be negative")
+		}
+	case sourceScript:
+		if s.Script == nil {
+			errors = append(errors, "script is required")
+		} else if err := validateScriptSources([]ScriptSource{*s.Script}); err != nil {
+			errors = append(errors, err.Error())
+		}
+	case sourceStatic:
+		if s.Value == nil {
+			errors = append(errors, "value is required")
+		}
+	case sourceWebhook:
+	default:
+		errors = append(errors, fmt.Sprintf("unknown type '%s' (expected file, http, script, static or webhook)", s.Type))
+	}
+
+	switch s.Merge {
+	case "", mergeReplace, mergeDeep, mergeAppend, mergeDefaults:
+	default:
+		errors = append(errors, fmt.Sprintf("unknown merge strategy '%s' (expected replace, deep, append or defaults)", s.Merge))
+	}
+
+	if s.Namespace == "" && s.Type == sourceStatic {
+		if _, ok := s.Value.(map[string]interface{}); !ok {
+			errors = append(errors, "value must be an object unless namespace is set")
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, "; "))
+	}
+	return nil
+}
+
+// dataSources returns the view's pipeline: dataPath (if set) followed by the configured sources
+func (v View) dataSources() []DataSource {
+	sources := []DataSource{}
+	if v.DataPath != "" {
+		sources = append(sources, DataSource{Type: sourceFile, Path: v.DataPath})
+	}
+	return append(sources, v.Sources...)
+}
+
+// loadViewSources runs the view's sources in order and merges their results. A failing
+// source is skipped with a warning; the view only fails when every source failed.
+func loadViewSources(view View) (map[string]interface{}, error) {
+	rawData := make(map[string]interface{})
+	sources := view.dataSources()
+
+	var lastErr error
+	failed := 0
+	for _, source := range sources {
+		value, err := fetchDataSource(view.Name, source)
+		if err != nil {
+			log.Printf("Warning: View %s: %s failed: %v", view.Name, source.describe(), err)
+			lastErr = err
+			failed++
+			continue
+		}
+
+		var data map[string]interface{}
+		if source.Namespace != "" {
+			data = map[string]interface{}{source.Namespace: value}
+		} else if obj, ok := value.(map[string]interface{}); ok {
+			data = obj
+		} else {
+			log.Printf("Warning: View %s: %s returned %T, expected a JSON object (set namespace to keep it)", view.Name, source.describe(), value)
+			failed++
+			continue
+		}
+
+		mergeData(rawData, data, source.Merge)
+	}
+
+	if len(sources) > 0 && failed == len(sources) {
+		if lastErr == nil {
+			lastErr = fmt.Errorf("no source returned a JSON object")
+		}
+		return nil, lastErr
+	}
+	return rawData, nil
+}
+
+// fetchDataSource returns the decoded JSON value produced by a single source of a view
+func fetchDataSource(viewName string, source DataSource) (interface{}, error) {
+	switch source.Type {
+	case sourceFile:
+		data, err := os.ReadFile(source.Path)
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", source.Path, err)
+		}
+		return value, nil
+
+	case sourceHTTP:
+		timeout := defaultHTTPSourceTimeout
+		if source.TimeoutSecs > 0 {
+			timeout = time.Duration(source.TimeoutSecs) * time.Second
+		}
+		req, err := http.NewRequest(http.MethodGet, source.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		for k, v := range source.Headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := (&http.Client{Timeout: timeout}).Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		var value interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&value); err != nil {
+			return nil, fmt.Errorf("invalid JSON response: %w", err)
+		}
+		return value, nil
+
+	case sourceScript:
+		return runScript(*source.Script)
+
+	case sourceStatic:
+		// Copy so merges never modify the configured value
+		return cloneJSONValue(source.Value), nil
+
+	case sourceWebhook:
+		// Nothing pushed yet is not an error; the view renders without it
+		data, ok := webhookStore.Get(viewName)
+		if !ok {
+			return map[string]interface{}{}, nil
+		}
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("unknown source type '%s'", source.Type)
+}
+
+// mergeData merges src into dst using the given strategy
+func mergeData(dst, src map[string]interface{}, strategy string) {
+	for k, v := range src {
+		existing, exists := dst[k]
+		switch strategy {
+		case mergeDefaults:
+			if !exists {
+				dst[k] = v
+			}
+		case mergeDeep, mergeAppend:
+			dstMap, dstIsMap := existing.(map[string]interface{})
+			srcMap, srcIsMap := v.(map[string]interface{})
+			if dstIsMap && srcIsMap {
+				mergeData(dstMap, srcMap, strategy)
+				continue
+			}
+			dstArr, dstIsArr := existing.([]interface{})
+			srcArr, srcIsArr := v.([]interface{})
+			if strategy == mergeAppend && dstIsArr && srcIsArr {
+				dst[k] = append(append([]interface{}{}, dstArr...), srcArr...)
+				continue
+			}
+			dst[k] = v
+		default:
+			dst[k] = v
+		}
+	}
+}
+
+func cloneJSONValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		clone := make(map[string]interface{}, len(value))
+		for k, item := range value {
+			clone[k] = cloneJSONValue(item)
+		}
+		return clone
+	case []interface{}:
+		clone := make([]interface{}, len(value))
+		for i, item := range value {
+			clone[i] = cloneJSONValue(item)
+		}
+		return clone
+	}
+	return v
+}
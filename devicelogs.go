@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DeviceLogEntry is one log entry posted by a device, stored as a line of JSON
+type DeviceLogEntry struct {
+	Received time.Time       `json:"received"`
+	Time     *time.Time      `json:"time,omitempty"` // creation_timestamp, if the device's clock was set
+	Message  string          `json:"message,omitempty"`
+	Source   string          `json:"source,omitempty"` // log_sourcefile:log_codeline
+	Entry    json.RawMessage `json:"entry"`            // The entry as sent by the firmware
+}
+
+// DeviceLogStore appends device logs to one file per device (<dir>/<key>.log), rotating to
+// <key>.log.1, .2, ... when a file reaches the size cap
+type DeviceLogStore struct {
+	mu sync.Mutex
+}
+
+var deviceLogStore = &DeviceLogStore{}
+
+// maxDeviceLogBody bounds a single POST to /api/log
+const maxDeviceLogBody = 1 << 20
+
+// deviceLogFileName keeps device keys (friendly IDs, MACs) safe as file names
+var deviceLogFileName = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// deviceLogSettings returns the log directory, the size cap per file and the number of files
+// kept per device
+func deviceLogSettings() (dir string, maxBytes int64, maxFiles int) {
+	dir = config.DeviceLogs.Dir
+	if dir == "" {
+		dir = "./logs"
+	}
+	maxKB := config.DeviceLogs.MaxFileKB
+	if maxKB == 0 {
+		maxKB = 512
+	}
+	maxFiles = config.DeviceLogs.MaxFiles
+	if maxFiles == 0 {
+		maxFiles = 4
+	}
+	return dir, int64(maxKB) * 1024, maxFiles
+}
+
+// validateDeviceLogSettings checks the deviceLogs section of config.json
+func validateDeviceLogSettings(cfg Config) error {
+	if cfg.DeviceLogs.MaxFileKB < 0 {
+		return fmt.Errorf("maxFileKB must not be negative")
+	}
+	if cfg.DeviceLogs.MaxFiles < 0 {
+		return fmt.Errorf("maxFiles must not be negative")
+	}
+	return nil
+}
+
+// deviceLogEntries extracts the entries from a firmware log post. Firmware sends
+// {"logs": [...]} or the older {"log": {"logs_array": [...]}}; anything else is kept as a
+// single entry.
+func deviceLogEntries(body []byte) ([]json.RawMessage, error) {
+	var payload struct {
+		Logs []json.RawMessage `json:"logs"`
+		Log  struct {
+			LogsArray []json.RawMessage `json:"logs_array"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	if len(payload.Logs) > 0 {
+		return payload.Logs, nil
+	}
+	if len(payload.Log.LogsArray) > 0 {
+		return payload.Log.LogsArray, nil
+	}
+	return []json.RawMessage{json.RawMessage(body)}, nil
+}
+
+// newDeviceLogEntry pulls the message, source location and timestamp out of a firmware entry
+func newDeviceLogEntry(raw json.RawMessage, received time.Time) DeviceLogEntry {
+	entry := DeviceLogEntry{Received: received, Entry: raw}
+
+	var fields struct {
+		Message    string      `json:"log_message"`
+		SourceFile string      `json:"log_sourcefile"`
+		CodeLine   interface{} `json:"log_codeline"`
+		Created    interface{} `json:"creation_timestamp"`
+	}
+	// Fields of unexpected types are skipped; the raw entry is kept either way
+	json.Unmarshal(raw, &fields)
+	entry.Message = fields.Message
+	if fields.SourceFile != "" {
+		entry.Source = fields.SourceFile
+		if fields.CodeLine != nil {
+			entry.Source += fmt.Sprintf(":%v", fields.CodeLine)
+		}
+	}
+	// Devices that haven't synced their clock report seconds since boot
+	if created, ok := fields.Created.(float64); ok && created > 1e9 {
+		t := time.Unix(int64(created), 0)
+		entry.Time = &t
+	}
+	return entry
+}
+
+// Append writes entries to the device's log, rotating files that exceed the size cap
+func (s *DeviceLogStore) Append(key string, entries []DeviceLogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir, maxBytes, maxFiles := deviceLogSettings()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	path := deviceLogPath(dir, key)
+
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		line = append(line, '\n')
+
+		if info, err := os.Stat(path); err == nil && info.Size() > 0 && info.Size()+int64(len(line)) > maxBytes {
+			rotateDeviceLog(path, maxFiles)
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open device log: %w", err)
+		}
+		_, err = file.Write(line)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write device log: %w", err)
+		}
+	}
+	return nil
+}
+
+// Read returns the newest limit entries of the device's log, oldest first
+func (s *DeviceLogStore) Read(key string, limit int) ([]DeviceLogEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir, _, maxFiles := deviceLogSettings()
+	path := deviceLogPath(dir, key)
+
+	entries := []DeviceLogEntry{}
+	for i := maxFiles - 1; i >= 0; i-- {
+		file := path
+		if i > 0 {
+			file = path + "." + strconv.Itoa(i)
+		}
+		read, err := readDeviceLogFile(file)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, read...)
+	}
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries, nil
+}
+
+func deviceLogPath(dir, key string) string {
+	return filepath.Join(dir, deviceLogFileName.ReplaceAllString(key, "-")+".log")
+}
+
+// rotateDeviceLog shifts <path>.N to <path>.N+1, dropping files beyond maxFiles
+func rotateDeviceLog(path string, maxFiles int) {
+	os.Remove(path + "." + strconv.Itoa(maxFiles-1))
+	for i := maxFiles - 2; i >= 1; i-- {
+		os.Rename(path+"."+strconv.Itoa(i), path+"."+strconv.Itoa(i+1))
+	}
+	if maxFiles > 1 {
+		os.Rename(path, path+".1")
+	} else {
+		os.Remove(path)
+	}
+}
+
+func readDeviceLogFile(path string) ([]DeviceLogEntry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device log: %w", err)
+	}
+	defer file.Close()
+
+	entries := []DeviceLogEntry{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxDeviceLogBody+1024)
+	for scanner.Scan() {
+		var entry DeviceLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// handleDeviceLogUpload serves POST /api/log, where the firmware sends its diagnostic logs
+// with the same Access-Token as /api/display
+func handleDeviceLogUpload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	device, ok := authenticateDevice(w, r)
+	if !ok {
+		return
+	}
+	key := telemetryKey(device, r)
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxDeviceLogBody))
+	if err != nil {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Log body too large"})
+		return
+	}
+	raw, err := deviceLogEntries(body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid JSON: " + err.Error()})
+		return
+	}
+
+	now := time.Now()
+	entries := make([]DeviceLogEntry, 0, len(raw))
+	for _, item := range raw {
+		entries = append(entries, newDeviceLogEntry(item, now))
+	}
+	if err := deviceLogStore.Append(key, entries); err != nil {
+		log.Printf("Warning: Failed to store logs of device %s: %v", key, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	log.Printf("Device %s: received %d log entries", key, len(entries))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeviceLogs serves GET /api/devices/<id>/logs?limit=N with the newest entries of a
+// device (default 100). id is a friendly ID or, for unregistered devices, their telemetry key.
+func handleDeviceLogs(w http.ResponseWriter, r *http.Request, key string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "limit must be a positive number"})
+			return
+		}
+		limit = n
+	}
+
+	entries, err := deviceLogStore.Read(key, limit)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"device": key,
+		"logs":   entries,
+	})
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Device is a TRMNL display registered through /api/setup, keyed by its MAC address
+type Device struct {
+	MAC             string    `json:"mac"`
+	APIKey          string    `json:"apiKey"`
+	FriendlyID      string    `json:"friendlyId"`
+	Name            string    `json:"name,omitempty"`
+	Views           []string  `json:"views,omitempty"`              // Playlist of view names (empty = follow the global rotation)
+	RefreshRateSecs int       `json:"refreshRateSeconds,omitempty"` // 0 = trmnl.refreshRateSeconds
+	Profile         string    `json:"profile,omitempty"`            // Output profile from render.profiles (empty = default)
+	FirmwareUpdates bool      `json:"firmwareUpdates,omitempty"`    // Offer firmware from the firmware directory
+	PinnedFirmware  string    `json:"pinnedFirmware,omitempty"`     // Firmware version to install (empty = newest)
+	CreatedAt       time.Time `json:"createdAt"`
+
+	// Rotation state for the device playlist (not persisted)
+	viewIndex    int
+	lastRotation time.Time
+	shownView    string // View announced by the last poll; the image fetch serves it
+
+	resetPending bool // Send reset_firmware on the next poll (not persisted)
+}
+
+// DeviceRegistry holds all known devices and persists them to a JSON file
+type DeviceRegistry struct {
+	mu      sync.Mutex
+	path    string
+	devices []*Device
+}
+
+var deviceRegistry *DeviceRegistry
+
+// friendlyIDAlphabet avoids characters that are easily confused on a small screen
+const friendlyIDAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// loadDeviceRegistry reads the registry from path, starting empty if the file doesn't exist yet
+func loadDeviceRegistry(path string) (*DeviceRegistry, error) {
+	registry := &DeviceRegistry{path: path}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return registry, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device registry %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, &registry.devices); err != nil {
+		return nil, fmt.Errorf("failed to parse device registry %s: %w", path, err)
+	}
+
+	for _, device := range registry.devices {
+		device.MAC = normalizeMAC(device.MAC)
+		for _, name := range device.Views {
+			if _, ok := findView(name); !ok {
+				log.Printf("Warning: Device %s (%s) references unknown view '%s'", device.FriendlyID, device.MAC, name)
+			}
+		}
+	}
+
+	return registry, nil
+}
+
+// Register returns the device for mac, creating it with fresh credentials on first setup
+func (r *DeviceRegistry) Register(mac string) (*Device, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	mac = normalizeMAC(mac)
+	for _, device := range r.devices {
+		if device.MAC == mac {
+			return device, false, nil
+		}
+	}
+
+	apiKey, err := randomHex(16)
+	if err != nil {
+		return nil, false, err
+	}
+	friendlyID, err := r.newFriendlyID()
+	if err != nil {
+		return nil, false, err
+	}
+
+	device := &Device{
+		MAC:        mac,
+		APIKey:     apiKey,
+		FriendlyID: friendlyID,
+		CreatedAt:  time.Now(),
+	}
+	r.devices = append(r.devices, device)
+
+	if err := r.save(); err != nil {
+		r.devices = r.devices[:len(r.devices)-1]
+		return nil, false, err
+	}
+
+	return device, true, nil
+}
+
+// ByToken resolves a device from its Access-Token
+func (r *DeviceRegistry) ByToken(token string) (*Device, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if token == "" {
+		return nil, false
+	}
+	for _, device := range r.devices {
+		if subtle.ConstantTimeCompare([]byte(device.APIKey), []byte(token)) == 1 {
+			return device, true
+		}
+	}
+	return nil, false
+}
+
+// authenticateDevice checks the Access-Token of a device request, writing the error response
+// when it is invalid. The global trmnl.apiKey returns a nil device; the key of a registered
+// device returns that device.
+func authenticateDevice(w http.ResponseWriter, r *http.Request) (*Device, bool) {
+	token := r.Header.Get("Access-Token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(config.TRMNL.APIKey)) == 1 {
+		return nil, true
+	}
+	if device, ok := deviceRegistry.ByToken(token); ok {
+		return device, true
+	}
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]string{"error": "Invalid Access-Token"})
+	return nil, false
+}
+
+// ByFriendlyID resolves a device from its friendly ID (used in per-device image URLs)
+func (r *DeviceRegistry) ByFriendlyID(friendlyID string) (*Device, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, device := range r.devices {
+		if strings.EqualFold(device.FriendlyID, friendlyID) {
+			return device, true
+		}
+	}
+	return nil, false
+}
+
+// List returns a snapshot of all registered devices
+func (r *DeviceRegistry) List() []Device {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]Device, 0, len(r.devices))
+	for _, device := range r.devices {
+		list = append(list, *device)
+	}
+	return list
+}
+
+// Snapshot returns a copy of the device for reading its settings without holding the lock
+func (r *DeviceRegistry) Snapshot(device *Device) Device {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *device
+}
+
+// SetFirmware changes the firmware update settings of a device and saves the registry
+func (r *DeviceRegistry) SetFirmware(device *Device, updates bool, pinned string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prevUpdates, prevPinned := device.FirmwareUpdates, device.PinnedFirmware
+	device.FirmwareUpdates = updates
+	device.PinnedFirmware = pinned
+	if err := r.save(); err != nil {
+		device.FirmwareUpdates, device.PinnedFirmware = prevUpdates, prevPinned
+		return err
+	}
+	return nil
+}
+
+// SetResetPending requests (or cancels) a reset of the device on its next poll
+func (r *DeviceRegistry) SetResetPending(device *Device, pending bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	device.resetPending = pending
+}
+
+// TakeReset reports whether a reset is pending for the device and clears it
+func (r *DeviceRegistry) TakeReset(device *Device) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pending := device.resetPending
+	device.resetPending = false
+	return pending
+}
+
+// playlist returns the configured views of the device's playlist
+func (d *Device) playlist() []View {
+	playlist := []View{}
+	for _, name := range d.Views {
+		if view, ok := findView(name); ok {
+			playlist = append(playlist, view)
+		}
+	}
+	return playlist
+}
+
+// CurrentView returns the view the device should display, advancing its playlist when the
+// current view's display duration has elapsed or its schedule no longer applies. ok is false
+// when the device has no playlist and should follow the global rotation instead. Only the
+// poll advances the playlist; the image fetch that follows uses ShownView.
+func (r *DeviceRegistry) CurrentView(device *Device) (View, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.advanceView(device)
+}
+
+// ShownView returns the playlist view announced by the device's last poll without advancing
+// the playlist, so the image matches the revision the poll reported. Before the first poll,
+// or when that view has left the playlist, it falls back to CurrentView.
+func (r *DeviceRegistry) ShownView(device *Device) (View, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, view := range device.playlist() {
+		if view.Name == device.shownView {
+			return view, true
+		}
+	}
+	return r.advanceView(device)
+}
+
+// advanceView implements CurrentView; r.mu must be held
+func (r *DeviceRegistry) advanceView(device *Device) (View, bool) {
+	playlist := device.playlist()
+	if len(playlist) == 0 {
+		return View{}, false
+	}
+
+	if device.viewIndex >= len(playlist) {
+		device.viewIndex = 0
+	}
+	if device.lastRotation.IsZero() {
+		device.lastRotation = time.Now()
+	}
+
+	// Same schedule and dwell rules as the global rotation
+	if next := pickView(playlist, device.viewIndex, device.lastRotation, time.Now()); next != device.viewIndex {
+		device.viewIndex = next
+		device.lastRotation = time.Now()
+		log.Printf("Device %s rotated to view: %s", device.FriendlyID, playlist[device.viewIndex].Name)
+	}
+
+	device.shownView = playlist[device.viewIndex].Name
+	return playlist[device.viewIndex], true
+}
+
+// NextViewChange returns when the device's playlist will show a different view, if that
+// happens within horizon. hasPlaylist is false for devices following the global rotation.
+func (r *DeviceRegistry) NextViewChange(device *Device, now time.Time, horizon time.Duration) (at time.Time, ok bool, hasPlaylist bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	playlist := device.playlist()
+	if len(playlist) == 0 {
+		return time.Time{}, false, false
+	}
+
+	// Playlists rotate on the poll, so a poll right at the change sees it
+	lastRotation := device.lastRotation
+	if lastRotation.IsZero() {
+		lastRotation = now
+	}
+	at, ok = nextViewChange(playlist, device.viewIndex%len(playlist), lastRotation, now, horizon)
+	return at, ok, true
+}
+
+// refreshRate returns the device polling interval in seconds
+func (d *Device) refreshRate() int {
+	if d.RefreshRateSecs > 0 {
+		return d.RefreshRateSecs
+	}
+	return config.TRMNL.RefreshRateSecs
+}
+
+// newFriendlyID generates a 6 character ID that isn't used by another device yet
+func (r *DeviceRegistry) newFriendlyID() (string, error) {
+	for attempt := 0; attempt < 100; attempt++ {
+		buf := make([]byte, 6)
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("failed to generate friendly ID: %w", err)
+		}
+		for i := range buf {
+			buf[i] = friendlyIDAlphabet[int(buf[i])%len(friendlyIDAlphabet)]
+		}
+		id := string(buf)
+
+		taken := false
+		for _, device := range r.devices {
+			if device.FriendlyID == id {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("failed to generate a unique friendly ID")
+}
+
+// save writes the registry atomically (temp file + rename). Caller must hold r.mu.
+func (r *DeviceRegistry) save() error {
+	data, err := json.MarshalIndent(r.devices, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(r.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create device registry directory: %w", err)
+		}
+	}
+
+	tempPath := r.path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write device registry: %w", err)
+	}
+	if err := os.Rename(tempPath, r.path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to atomically replace %s: %w", r.path, err)
+	}
+	return nil
+}
+
+func normalizeMAC(mac string) string {
+	return strings.ToUpper(strings.TrimSpace(mac))
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random key: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+)
+
+// Supported dithering modes for 1-bit and grayscale conversion
+const (
+	DitherThreshold      = "threshold"
+	DitherFloydSteinberg = "floyd-steinberg"
+	DitherAtkinson       = "atkinson"
+	DitherStucki         = "stucki"
+	DitherBayer4         = "bayer4"
+	DitherBayer8         = "bayer8"
+)
+
+// DitherSettings controls how grayscale renders are reduced to black and white (or to the
+// gray levels of an output profile).
+// Zero values mean "inherit": a view inherits from render settings, which
+// inherit from the defaults (threshold mode, threshold 128, gamma and contrast 1.0).
+type DitherSettings struct {
+	Mode      string  `json:"dither,omitempty"`
+	Threshold int     `json:"threshold,omitempty"`
+	Gamma     float64 `json:"gamma,omitempty"`
+	Contrast  float64 `json:"contrast,omitempty"`
+}
+
+// ditherKernel is one weighted neighbor of an error diffusion matrix
+type ditherKernel struct {
+	dx, dy int
+	weight float64
+}
+
+var errorDiffusionKernels = map[string][]ditherKernel{
+	DitherFloydSteinberg: {
+		{1, 0, 7.0 / 16}, {-1, 1, 3.0 / 16}, {0, 1, 5.0 / 16}, {1, 1, 1.0 / 16},
+	},
+	// Atkinson only diffuses 6/8 of the error, which keeps highlights crisp
+	DitherAtkinson: {
+		{1, 0, 1.0 / 8}, {2, 0, 1.0 / 8},
+		{-1, 1, 1.0 / 8}, {0, 1, 1.0 / 8}, {1, 1, 1.0 / 8},
+		{0, 2, 1.0 / 8},
+	},
+	DitherStucki: {
+		{1, 0, 8.0 / 42}, {2, 0, 4.0 / 42},
+		{-2, 1, 2.0 / 42}, {-1, 1, 4.0 / 42}, {0, 1, 8.0 / 42}, {1, 1, 4.0 / 42}, {2, 1, 2.0 / 42},
+		{-2, 2, 1.0 / 42}, {-1, 2, 2.0 / 42}, {0, 2, 4.0 / 42}, {1, 2, 2.0 / 42}, {2, 2, 1.0 / 42},
+	},
+}
+
+var bayer4 = [][]int{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+var bayer8 = [][]int{
+	{0, 32, 8, 40, 2, 34, 10, 42},
+	{48, 16, 56, 24, 50, 18, 58, 26},
+	{12, 44, 4, 36, 14, 46, 6, 38},
+	{60, 28, 52, 20, 62, 30, 54, 22},
+	{3, 35, 11, 43, 1, 33, 9, 41},
+	{51, 19, 59, 27, 49, 17, 57, 25},
+	{15, 47, 7, 39, 13, 45, 5, 37},
+	{63, 31, 55, 23, 61, 29, 53, 21},
+}
+
+// validateDitherSettings checks a (possibly partial) set of dither settings
+func validateDitherSettings(d DitherSettings) error {
+	errors := []string{}
+
+	switch d.Mode {
+	case "", DitherThreshold, DitherFloydSteinberg, DitherAtkinson, DitherStucki, DitherBayer4, DitherBayer8:
+	default:
+		errors = append(errors, fmt.Sprintf("unknown dither mode '%s' (expected one of: %s)", d.Mode,
+			strings.Join([]string{DitherThreshold, DitherFloydSteinberg, DitherAtkinson, DitherStucki, DitherBayer4, DitherBayer8}, ", ")))
+	}
+	if d.Threshold < 0 || d.Threshold > 255 {
+		errors = append(errors, fmt.Sprintf("threshold must be between 0 and 255, got %d", d.Threshold))
+	}
+	if d.Gamma < 0 {
+		errors = append(errors, fmt.Sprintf("gamma must be positive, got %g", d.Gamma))
+	}
+	if d.Contrast < 0 {
+		errors = append(errors, fmt.Sprintf("contrast must be positive, got %g", d.Contrast))
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, "; "))
+	}
+	return nil
+}
+
+// resolveDitherSettings layers view settings over the global render settings and defaults
+func resolveDitherSettings(view DitherSettings) DitherSettings {
+	resolved := DitherSettings{
+		Mode:      DitherThreshold,
+		Threshold: 128,
+		Gamma:     1.0,
+		Contrast:  1.0,
+	}
+
+	for _, layer := range []DitherSettings{config.Render.DitherSettings, view} {
+		if layer.Mode != "" {
+			resolved.Mode = layer.Mode
+		}
+		if layer.Threshold != 0 {
+			resolved.Threshold = layer.Threshold
+		}
+		if layer.Gamma != 0 {
+			resolved.Gamma = layer.Gamma
+		}
+		if layer.Contrast != 0 {
+			resolved.Contrast = layer.Contrast
+		}
+	}
+
+	return resolved
+}
+
+// ditherImage reduces a grayscale buffer (values 0-255, row-major) to a black and white
+// image, or to 4 or 16 evenly spaced grays for a bitDepth of 2 or 4
+func ditherImage(gray []float64, width, height int, settings DitherSettings, bitDepth int) *image.Gray {
+	// Tone adjustments before quantization
+	for i, v := range gray {
+		if settings.Gamma != 1.0 {
+			v = 255 * math.Pow(v/255, 1/settings.Gamma)
+		}
+		if settings.Contrast != 1.0 {
+			v = (v-128)*settings.Contrast + 128
+		}
+		gray[i] = v
+	}
+
+	out := image.NewGray(image.Rect(0, 0, width, height))
+	if bitDepth > 1 {
+		ditherLevels(gray, out, settings, 1<<bitDepth)
+		return out
+	}
+	threshold := float64(settings.Threshold)
+
+	switch settings.Mode {
+	case DitherBayer4, DitherBayer8:
+		matrix := bayer4
+		if settings.Mode == DitherBayer8 {
+			matrix = bayer8
+		}
+		n := len(matrix)
+		levels := float64(n * n)
+		// Shift the ordered threshold map so a custom threshold still biases the result
+		offset := threshold - 128
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				limit := (float64(matrix[y%n][x%n])+0.5)/levels*255 + offset
+				if gray[y*width+x] >= limit {
+					out.Pix[y*out.Stride+x] = 255
+				}
+			}
+		}
+
+	case DitherFloydSteinberg, DitherAtkinson, DitherStucki:
+		kernel := errorDiffusionKernels[settings.Mode]
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				old := gray[y*width+x]
+				newValue := 0.0
+				if old >= threshold {
+					newValue = 255
+					out.Pix[y*out.Stride+x] = 255
+				}
+				quantErr := old - newValue
+				for _, k := range kernel {
+					nx, ny := x+k.dx, y+k.dy
+					if nx < 0 || nx >= width || ny >= height {
+						continue
+					}
+					gray[ny*width+nx] += quantErr * k.weight
+				}
+			}
+		}
+
+	default: // DitherThreshold
+		for i, v := range gray {
+			if v >= threshold {
+				out.Pix[(i/width)*out.Stride+i%width] = 255
+			}
+		}
+	}
+
+	return out
+}
+
+// ditherLevels quantizes to the given number of gray levels with the same modes as the
+// 1-bit conversion. The threshold shifts every level boundary, like it does for Bayer.
+func ditherLevels(gray []float64, out *image.Gray, settings DitherSettings, levels int) {
+	width, height := out.Rect.Dx(), out.Rect.Dy()
+	step := 255 / float64(levels-1)
+	offset := float64(settings.Threshold) - 128
+	quantize := func(v float64) float64 {
+		level := math.Round((v - offset) / step)
+		return math.Max(0, math.Min(float64(levels-1), level)) * step
+	}
+
+	switch settings.Mode {
+	case DitherBayer4, DitherBayer8:
+		matrix := bayer4
+		if settings.Mode == DitherBayer8 {
+			matrix = bayer8
+		}
+		n := len(matrix)
+		cells := float64(n * n)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				bias := ((float64(matrix[y%n][x%n])+0.5)/cells - 0.5) * step
+				out.Pix[y*out.Stride+x] = uint8(math.Round(quantize(gray[y*width+x] - bias)))
+			}
+		}
+
+	case DitherFloydSteinberg, DitherAtkinson, DitherStucki:
+		kernel := errorDiffusionKernels[settings.Mode]
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				old := gray[y*width+x]
+				newValue := quantize(old)
+				out.Pix[y*out.Stride+x] = uint8(math.Round(newValue))
+				quantErr := old - newValue
+				for _, k := range kernel {
+					nx, ny := x+k.dx, y+k.dy
+					if nx < 0 || nx >= width || ny >= height {
+						continue
+					}
+					gray[ny*width+nx] += quantErr * k.weight
+				}
+			}
+		}
+
+	default: // DitherThreshold
+		for i, v := range gray {
+			out.Pix[(i/width)*out.Stride+i%width] = uint8(math.Round(quantize(v)))
+		}
+	}
+}
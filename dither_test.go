@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// flatGray returns a width x height buffer filled with one gray value
+func flatGray(width, height int, v float64) []float64 {
+	gray := make([]float64, width*height)
+	for i := range gray {
+		gray[i] = v
+	}
+	return gray
+}
+
+// ditherSettings returns resolved settings without tone adjustments
+func ditherSettings(mode string, threshold int) DitherSettings {
+	return DitherSettings{Mode: mode, Threshold: threshold, Gamma: 1.0, Contrast: 1.0}
+}
+
+// assertDither runs ditherImage and compares the output rows, top row first
+func assertDither(t *testing.T, gray []float64, width int, settings DitherSettings, bitDepth int, want [][]uint8) {
+	t.Helper()
+	out := ditherImage(gray, width, len(want), settings, bitDepth)
+	for y, row := range want {
+		got := out.Pix[y*out.Stride : y*out.Stride+width]
+		if !bytes.Equal(got, row) {
+			t.Errorf("%s (%d bit) row %d = %v, want %v", settings.Mode, bitDepth, y, got, row)
+		}
+	}
+}
+
+func TestDitherThreshold(t *testing.T) {
+	assertDither(t, []float64{0, 127, 128, 255}, 4, ditherSettings(DitherThreshold, 128), 1, [][]uint8{
+		{0, 0, 255, 255},
+	})
+
+	// Gamma 2.2 lifts 64 to 136; contrast 2 pushes 110 down to 92
+	gamma := ditherSettings(DitherThreshold, 128)
+	gamma.Gamma = 2.2
+	assertDither(t, []float64{64, 32}, 2, gamma, 1, [][]uint8{{255, 0}})
+
+	contrast := ditherSettings(DitherThreshold, 100)
+	contrast.Contrast = 2
+	assertDither(t, []float64{110, 150}, 2, contrast, 1, [][]uint8{{0, 255}})
+}
+
+func TestDitherErrorDiffusionGolden(t *testing.T) {
+	// 3x2 of gray 100: the kernels spread the error differently
+	tests := []struct {
+		mode string
+		want [][]uint8
+	}{
+		{DitherFloydSteinberg, [][]uint8{
+			{0, 255, 0}, // 100, 143.75, 51.33
+			{0, 255, 0}, // 110.39, 129.41, 54.14
+		}},
+		// Only 6/8 of the error is passed on
+		{DitherAtkinson, [][]uint8{
+			{0, 0, 0},     // 100, 112.5, 126.56
+			{0, 255, 255}, // 126.56, 158.2, 133.6
+		}},
+		{DitherStucki, [][]uint8{
+			{0, 0, 255}, // 100, 119.05, 132.2
+			{0, 255, 0}, // 124.54, 144.23, 83.47
+		}},
+	}
+	for _, tt := range tests {
+		assertDither(t, flatGray(3, 2, 100), 3, ditherSettings(tt.mode, 128), 1, tt.want)
+	}
+}
+
+func TestDitherBayerGolden(t *testing.T) {
+	// Mid gray is white where the matrix entry is below half its range: a checkerboard
+	assertDither(t, flatGray(4, 4, 128), 4, ditherSettings(DitherBayer4, 128), 1, [][]uint8{
+		{255, 0, 255, 0}, // 0 8 2 10
+		{0, 255, 0, 255}, // 12 4 14 6
+		{255, 0, 255, 0}, // 3 11 1 9
+		{0, 255, 0, 255}, // 15 7 13 5
+	})
+
+	// A higher threshold shifts the map: only entries 0-5 stay white
+	assertDither(t, flatGray(4, 4, 128), 4, ditherSettings(DitherBayer4, 160), 1, [][]uint8{
+		{255, 0, 255, 0},
+		{0, 255, 0, 0},
+		{255, 0, 255, 0},
+		{0, 0, 0, 255},
+	})
+
+	// 64 of 255 is white for entries 0-15 of the 8x8 map
+	assertDither(t, flatGray(8, 2, 64), 8, ditherSettings(DitherBayer8, 128), 1, [][]uint8{
+		{255, 0, 255, 0, 255, 0, 255, 0}, // 0 32 8 40 2 34 10 42
+		{0, 0, 0, 0, 0, 0, 0, 0},         // 48 16 56 24 50 18 58 26
+	})
+}
+
+func TestDitherLevelsGolden(t *testing.T) {
+	// 4 levels, 85 apart: values round to the nearest level
+	assertDither(t, []float64{0, 42, 43, 127, 128, 170, 213, 255}, 8, ditherSettings(DitherThreshold, 128), 2, [][]uint8{
+		{0, 0, 85, 85, 170, 170, 255, 255},
+	})
+
+	// 16 levels, 17 apart
+	assertDither(t, []float64{8, 9, 136, 250}, 4, ditherSettings(DitherThreshold, 128), 4, [][]uint8{
+		{0, 17, 136, 255},
+	})
+
+	// Error diffusion between levels: 60, 49.06 / 45.45, 29.9
+	assertDither(t, flatGray(2, 2, 60), 2, ditherSettings(DitherFloydSteinberg, 128), 2, [][]uint8{
+		{85, 85},
+		{85, 0},
+	})
+
+	// The Bayer map alternates between the two nearest levels
+	assertDither(t, flatGray(4, 2, 128), 4, ditherSettings(DitherBayer4, 128), 2, [][]uint8{
+		{170, 85, 170, 85},
+		{85, 170, 85, 170},
+	})
+}
+
+func TestResolveDitherSettings(t *testing.T) {
+	useTestConfig(t)
+
+	if got, want := resolveDitherSettings(DitherSettings{}), ditherSettings(DitherThreshold, 128); got != want {
+		t.Errorf("defaults = %+v, want %+v", got, want)
+	}
+
+	// The view overrides the render settings field by field; unset fields fall through
+	config.Render.DitherSettings = DitherSettings{Mode: DitherAtkinson, Gamma: 1.5, Contrast: 1.2}
+	got := resolveDitherSettings(DitherSettings{Threshold: 100, Gamma: 2})
+	want := DitherSettings{Mode: DitherAtkinson, Threshold: 100, Gamma: 2, Contrast: 1.2}
+	if got != want {
+		t.Errorf("resolved = %+v, want %+v", got, want)
+	}
+}
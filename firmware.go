@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FirmwareImage is a .bin file in the firmware directory. The version is taken from the
+// file name, e.g. trmnl-1.5.2.bin or FW1.5.2.bin.
+type FirmwareImage struct {
+	File     string    `json:"file"`
+	Version  string    `json:"version"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+// firmwareVersionPattern finds the version in a firmware file name
+var firmwareVersionPattern = regexp.MustCompile(`\d+(?:\.\d+)+`)
+
+// firmwareDir returns the directory holding firmware files (default: ./firmware)
+func firmwareDir() string {
+	if config.Firmware.Dir != "" {
+		return config.Firmware.Dir
+	}
+	return "./firmware"
+}
+
+// firmwareRolloutPercent returns the share of opted-in devices offered a new version
+func firmwareRolloutPercent() int {
+	if config.Firmware.RolloutPercent == nil {
+		return 100
+	}
+	return *config.Firmware.RolloutPercent
+}
+
+// validateFirmwareSettings checks the firmware section of config.json
+func validateFirmwareSettings(cfg Config) error {
+	if p := cfg.Firmware.RolloutPercent; p != nil && (*p < 0 || *p > 100) {
+		return fmt.Errorf("rolloutPercent must be between 0 and 100")
+	}
+	return nil
+}
+
+// listFirmware returns the firmware images in the firmware directory, oldest version first.
+// A missing directory means no firmware.
+func listFirmware() ([]FirmwareImage, error) {
+	entries, err := os.ReadDir(firmwareDir())
+	if os.IsNotExist(err) {
+		return []FirmwareImage{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read firmware directory: %w", err)
+	}
+
+	images := []FirmwareImage{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".bin") {
+			continue
+		}
+		version := firmwareVersionPattern.FindString(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+		if version == "" {
+			log.Printf("Warning: Ignoring firmware %s: no version in the file name", entry.Name())
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		images = append(images, FirmwareImage{
+			File:     entry.Name(),
+			Version:  version,
+			Size:     info.Size(),
+			Modified: info.ModTime(),
+		})
+	}
+	sort.Slice(images, func(i, j int) bool {
+		return compareVersions(images[i].Version, images[j].Version) < 0
+	})
+	return images, nil
+}
+
+// compareVersions compares dotted version numbers ("1.5.10" > "1.5.2"). A leading "v" and
+// suffixes like "-beta" are ignored.
+func compareVersions(a, b string) int {
+	pa := versionParts(a)
+	pb := versionParts(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(version string) []int {
+	parts := []int{}
+	for _, field := range strings.Split(firmwareVersionPattern.FindString(version), ".") {
+		n, _ := strconv.Atoi(field)
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// firmwareTarget returns the firmware a device should run: its pinned version, or the
+// newest image
+func firmwareTarget(device Device) (FirmwareImage, bool) {
+	images, err := listFirmware()
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return FirmwareImage{}, false
+	}
+	if len(images) == 0 {
+		return FirmwareImage{}, false
+	}
+	if device.PinnedFirmware == "" {
+		return images[len(images)-1], true
+	}
+	for _, image := range images {
+		if compareVersions(image.Version, device.PinnedFirmware) == 0 {
+			return image, true
+		}
+	}
+	return FirmwareImage{}, false
+}
+
+// firmwareOffer returns the update to offer a device reporting the current version. Only
+// devices that opted in get updates, and only if they fall into the rollout.
+func firmwareOffer(device Device, current string) (FirmwareImage, bool) {
+	if !device.FirmwareUpdates || current == "" {
+		return FirmwareImage{}, false
+	}
+	target, ok := firmwareTarget(device)
+	if !ok || compareVersions(target.Version, current) <= 0 {
+		return FirmwareImage{}, false
+	}
+	if !inFirmwareRollout(device, target.Version) {
+		return FirmwareImage{}, false
+	}
+	return target, true
+}
+
+// inFirmwareRollout picks a stable share of devices per version, so raising rolloutPercent
+// adds devices without dropping the ones already updated
+func inFirmwareRollout(device Device, version string) bool {
+	h := fnv.New32a()
+	h.Write([]byte(device.FriendlyID + "@" + version))
+	return int(h.Sum32()%100) < firmwareRolloutPercent()
+}
+
+// serveFirmware serves GET /firmware/<file>.bin
+func serveFirmware(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/firmware/")
+	images, err := listFirmware()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, image := range images {
+		if image.File == name {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			http.ServeFile(w, r, filepath.Join(firmwareDir(), image.File))
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// handleFirmwareList serves GET /api/firmware: the available images and the rollout
+func handleFirmwareList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	images, err := listFirmware()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"dir":            firmwareDir(),
+		"rolloutPercent": firmwareRolloutPercent(),
+		"firmware":       images,
+	})
+}
+
+// handleDeviceFirmware serves /api/devices/<id>/firmware: GET shows the firmware state of
+// a device, PUT sets {"updates": true, "version": "1.5.2"} (bearer token required)
+func handleDeviceFirmware(w http.ResponseWriter, r *http.Request, device *Device) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		if !requireToken(w, r, "Device admin actions") {
+			return
+		}
+		var req struct {
+			Updates bool   `json:"updates"`
+			Version string `json:"version"` // Empty = newest
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid JSON: " + err.Error()})
+			return
+		}
+		if err := deviceRegistry.SetFirmware(device, req.Updates, req.Version); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		log.Printf("Device %s: firmware updates %v (version %q)", device.FriendlyID, req.Updates, req.Version)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	snapshot := deviceRegistry.Snapshot(device)
+	response := map[string]interface{}{
+		"device":  snapshot.FriendlyID,
+		"updates": snapshot.FirmwareUpdates,
+		"version": snapshot.PinnedFirmware,
+	}
+	reported := ""
+	if sample, ok := telemetryStore.Latest(snapshot.FriendlyID); ok {
+		reported = sample.FirmwareVersion
+		response["reportedVersion"] = reported
+	}
+	if target, ok := firmwareTarget(snapshot); ok {
+		response["target"] = target
+		response["inRollout"] = inFirmwareRollout(snapshot, target.Version)
+	}
+	if offer, ok := firmwareOffer(snapshot, reported); ok {
+		response["offered"] = offer.Version
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleDeviceReset serves /api/devices/<id>/reset: POST makes the next /api/display poll
+// tell the device to reset, DELETE cancels a pending reset (bearer token required)
+func handleDeviceReset(w http.ResponseWriter, r *http.Request, device *Device) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireToken(w, r, "Device admin actions") {
+		return
+	}
+
+	pending := r.Method == http.MethodPost
+	deviceRegistry.SetResetPending(device, pending)
+	if pending {
+		log.Printf("Device %s: reset requested, sent on its next poll", device.FriendlyID)
+	} else {
+		log.Printf("Device %s: reset cancelled", device.FriendlyID)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":      true,
+		"device":       device.FriendlyID,
+		"resetPending": pending,
+	})
+}
@@ -0,0 +1,1396 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gomedium"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/net/html"
+)
+
+// GoRenderer renders the HTML/CSS subset used by the bundled templates (text, boxes,
+// borders, flexbox and simple grids) without a browser, using the embedded Go fonts.
+// Unsupported markup (images, floats, positioning, transforms) is ignored.
+type GoRenderer struct{}
+
+func (GoRenderer) Name() string { return "go" }
+
+func (GoRenderer) Render(src string, width, height int) (image.Image, error) {
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+
+	rootBox, err := layoutDocument(src, width, height)
+	if err != nil {
+		return nil, err
+	}
+	if rootBox != nil {
+		rootBox.paint(canvas, canvas.Bounds())
+	}
+	return canvas, nil
+}
+
+// layoutDocument parses src and lays it out in a width x height viewport. It returns nil
+// for a document without elements.
+func layoutDocument(src string, width, height int) (*box, error) {
+	doc, err := html.Parse(strings.NewReader(src))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	// Collect <style> blocks in document order
+	rules := []cssRule{}
+	var collectStyles func(*html.Node)
+	collectStyles = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "style" {
+			text := ""
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				text += c.Data
+			}
+			rules = append(rules, parseStylesheet(text, len(rules))...)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collectStyles(c)
+		}
+	}
+	collectStyles(doc)
+
+	root := buildStyledTree(doc, rules)
+	if root == nil {
+		return nil, nil
+	}
+
+	rootBox := newBox(root, 1)
+	rootBox.children = buildChildBoxes(rootBox, root)
+	rootBox.layout(0, 0, float64(width), float64(height), float64(height))
+	return rootBox, nil
+}
+
+// ---------------------------------------------------------------------------
+// Boxes
+
+const (
+	displayBlock  = "block"
+	displayFlex   = "flex"
+	displayGrid   = "grid"
+	displayInline = "inline" // Block container holding only inline content (laid out as lines)
+)
+
+type boxStyle struct {
+	display        string
+	fontSize       float64
+	lineHeight     float64
+	letterSpacing  float64
+	fontWeight     int
+	color          color.RGBA
+	background     color.RGBA
+	hasBackground  bool
+	opacity        float64 // Effective opacity (multiplied down the tree)
+	margin         [4]float64
+	padding        [4]float64
+	border         [4]float64
+	borderColor    [4]color.RGBA
+	width          string
+	height         string
+	minHeight      string
+	maxHeight      string
+	maxWidth       string
+	flexDirection  string
+	justifyContent string
+	alignItems     string
+	alignSelf      string
+	flexGrow       float64
+	flexShrink     float64
+	flexBasis      string
+	rowGap         float64
+	columnGap      float64
+	gridColumns    string
+	gridSpan       int
+	textTransform  string
+	textAlign      string
+	nowrap         bool
+	lineThrough    bool
+	ellipsis       bool
+	overflowHidden bool
+	hidden         bool
+}
+
+type textRun struct {
+	text  string
+	style *boxStyle
+	br    bool // Forced line break
+}
+
+type textFragment struct {
+	text  string
+	style *boxStyle
+	x     float64
+	width float64
+}
+
+type textLine struct {
+	y         float64
+	height    float64
+	baseline  float64
+	fragments []textFragment
+}
+
+type box struct {
+	node     *styledNode // nil for anonymous blocks
+	st       boxStyle
+	children []*box
+	runs     []textRun
+	lines    []textLine
+
+	x, y, w, h float64
+	cbHeight   float64 // Containing block height for percentage heights (-1 = indefinite)
+}
+
+func newBox(n *styledNode, parentOpacity float64) *box {
+	b := &box{node: n, st: computeBoxStyle(n.props, parentOpacity), cbHeight: -1}
+	return b
+}
+
+func computeBoxStyle(p map[string]string, parentOpacity float64) boxStyle {
+	fontSize := parseLength(p["font-size"], 16, 16, 16)
+	st := boxStyle{
+		display:        p["display"],
+		fontSize:       fontSize,
+		fontWeight:     parseFontWeight(p["font-weight"]),
+		color:          color.RGBA{0, 0, 0, 255},
+		opacity:        parentOpacity,
+		width:          p["width"],
+		height:         p["height"],
+		minHeight:      p["min-height"],
+		maxHeight:      p["max-height"],
+		maxWidth:       p["max-width"],
+		flexDirection:  p["flex-direction"],
+		justifyContent: p["justify-content"],
+		alignItems:     p["align-items"],
+		alignSelf:      p["align-self"],
+		flexGrow:       parseFloatDefault(p["flex-grow"], 0),
+		flexShrink:     parseFloatDefault(p["flex-shrink"], 1),
+		flexBasis:      p["flex-basis"],
+		gridColumns:    p["grid-template-columns"],
+		gridSpan:       1,
+		textTransform:  p["text-transform"],
+		textAlign:      p["text-align"],
+		nowrap:         p["white-space"] == "nowrap" || p["white-space"] == "pre",
+		lineThrough:    strings.Contains(p["text-decoration"], "line-through"),
+		ellipsis:       p["text-overflow"] == "ellipsis",
+		overflowHidden: p["overflow"] == "hidden" || p["overflow"] == "clip",
+		hidden:         p["visibility"] == "hidden",
+	}
+
+	if c, ok := parseCSSColor(p["color"]); ok {
+		st.color = c
+	}
+	if c, ok := parseCSSColor(p["background-color"]); ok && c.A > 0 {
+		st.background = c
+		st.hasBackground = true
+	}
+	if v, ok := p["opacity"]; ok {
+		st.opacity *= parseFloatDefault(v, 1)
+	}
+
+	st.lineHeight = fontSize * 1.2
+	if v, ok := p["line-height"]; ok && v != "normal" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			st.lineHeight = fontSize * f
+		} else {
+			st.lineHeight = parseLength(v, fontSize, fontSize, 16)
+		}
+	}
+	if v, ok := p["letter-spacing"]; ok && v != "normal" {
+		st.letterSpacing = parseLength(v, 0, fontSize, 16)
+	}
+
+	for i, side := range boxSides {
+		st.margin[i] = parseLength(p["margin-"+side], 0, fontSize, 16)
+		st.padding[i] = parseLength(p["padding-"+side], 0, fontSize, 16)
+		st.borderColor[i] = st.color
+		if c, ok := parseCSSColor(p["border-"+side+"-color"]); ok {
+			st.borderColor[i] = c
+		}
+		switch w := p["border-"+side+"-width"]; w {
+		case "", "0":
+		case "thin":
+			st.border[i] = 1
+		case "medium":
+			st.border[i] = 3
+		case "thick":
+			st.border[i] = 5
+		default:
+			st.border[i] = parseLength(w, 0, fontSize, 16)
+		}
+	}
+
+	rowGap := p["row-gap"]
+	colGap := p["column-gap"]
+	st.rowGap = parseLength(rowGap, 0, fontSize, 16)
+	st.columnGap = parseLength(colGap, 0, fontSize, 16)
+
+	if span := strings.TrimPrefix(p["grid-column"], "span "); span != p["grid-column"] {
+		if n, err := strconv.Atoi(strings.TrimSpace(span)); err == nil && n > 0 {
+			st.gridSpan = n
+		}
+	}
+
+	switch st.display {
+	case "flex", "inline-flex":
+		st.display = displayFlex
+	case "grid", "inline-grid":
+		st.display = displayGrid
+	case "none", "contents", "inline":
+	default:
+		st.display = displayBlock
+	}
+
+	return st
+}
+
+// buildChildBoxes creates the boxes for n's children inside parent
+func buildChildBoxes(parent *box, n *styledNode) []*box {
+	isContainer := parent.st.display == displayFlex || parent.st.display == displayGrid
+
+	// Flatten display: contents and drop display: none
+	items := flattenChildren(n)
+
+	if !isContainer {
+		allInline := true
+		for _, c := range items {
+			if c.tag != "" && c.props["display"] != "inline" {
+				allInline = false
+				break
+			}
+		}
+		if allInline {
+			parent.st.display = displayInline
+			parent.runs = collectRuns(items, &parent.st)
+			return nil
+		}
+	}
+
+	boxes := []*box{}
+	pendingInline := []*styledNode{}
+	flushInline := func() {
+		if len(pendingInline) == 0 {
+			return
+		}
+		anon := &box{st: anonymousStyle(&parent.st), cbHeight: -1}
+		anon.runs = collectRuns(pendingInline, &anon.st)
+		pendingInline = nil
+		if runsHaveText(anon.runs) {
+			boxes = append(boxes, anon)
+		}
+	}
+
+	for _, c := range items {
+		// Inline content in a block becomes an anonymous block; flex/grid items are blockified
+		if c.tag == "" || (c.props["display"] == "inline" && !isContainer) {
+			pendingInline = append(pendingInline, c)
+			continue
+		}
+		flushInline()
+
+		child := newBox(c, parent.st.opacity)
+		if child.st.display == "inline" {
+			child.st.display = displayBlock
+		}
+		child.children = buildChildBoxes(child, c)
+		boxes = append(boxes, child)
+	}
+	flushInline()
+
+	return boxes
+}
+
+func flattenChildren(n *styledNode) []*styledNode {
+	items := []*styledNode{}
+	for _, c := range n.children {
+		if c.tag == "" {
+			items = append(items, c)
+			continue
+		}
+		switch c.props["display"] {
+		case "none":
+		case "contents":
+			items = append(items, flattenChildren(c)...)
+		default:
+			if c.tag == "br" {
+				items = append(items, c)
+				continue
+			}
+			items = append(items, c)
+		}
+	}
+	return items
+}
+
+// anonymousStyle inherits the text properties of the parent box without any box decoration
+func anonymousStyle(parent *boxStyle) boxStyle {
+	return boxStyle{
+		display:       displayInline,
+		fontSize:      parent.fontSize,
+		lineHeight:    parent.lineHeight,
+		letterSpacing: parent.letterSpacing,
+		fontWeight:    parent.fontWeight,
+		color:         parent.color,
+		opacity:       parent.opacity,
+		textTransform: parent.textTransform,
+		textAlign:     parent.textAlign,
+		nowrap:        parent.nowrap,
+		lineThrough:   parent.lineThrough,
+		ellipsis:      parent.ellipsis,
+		flexShrink:    1,
+		gridSpan:      1,
+	}
+}
+
+// collectRuns gathers text from inline nodes, keeping each element's text style
+func collectRuns(nodes []*styledNode, blockStyle *boxStyle) []textRun {
+	runs := []textRun{}
+	for _, c := range nodes {
+		if c.tag == "" {
+			runs = append(runs, textRun{text: c.text, style: blockStyle})
+			continue
+		}
+		if c.tag == "br" {
+			runs = append(runs, textRun{br: true, style: blockStyle})
+			continue
+		}
+		if c.props["display"] == "none" {
+			continue
+		}
+		st := computeBoxStyle(c.props, blockStyle.opacity)
+		st.textAlign = blockStyle.textAlign
+		st.nowrap = st.nowrap || blockStyle.nowrap
+		runs = append(runs, collectRuns(flattenChildren(c), &st)...)
+	}
+	return runs
+}
+
+func runsHaveText(runs []textRun) bool {
+	for _, r := range runs {
+		if r.br || strings.TrimSpace(r.text) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ---------------------------------------------------------------------------
+// Layout
+
+func (b *box) edges(side int) float64 {
+	return b.st.border[side] + b.st.padding[side]
+}
+
+func (b *box) horizontalEdges() float64 { return b.edges(1) + b.edges(3) }
+func (b *box) verticalEdges() float64   { return b.edges(0) + b.edges(2) }
+
+// specifiedWidth resolves the CSS width against the containing block width
+func (b *box) specifiedWidth(containerW float64) (float64, bool) {
+	v := b.st.width
+	if v == "" || v == "auto" {
+		return 0, false
+	}
+	w := parseLength(v, containerW, b.st.fontSize, 16)
+	if b.st.maxWidth != "" && b.st.maxWidth != "none" {
+		w = math.Min(w, parseLength(b.st.maxWidth, containerW, b.st.fontSize, 16))
+	}
+	return w, true
+}
+
+// specifiedHeight resolves the CSS height (percentages need a definite containing block)
+func (b *box) specifiedHeight() (float64, bool) {
+	v := b.st.height
+	if v == "" || v == "auto" {
+		return 0, false
+	}
+	if strings.HasSuffix(v, "%") && b.cbHeight < 0 {
+		return 0, false
+	}
+	return parseLength(v, b.cbHeight, b.st.fontSize, 16), true
+}
+
+func (b *box) clampHeight(h float64) float64 {
+	if v := b.st.maxHeight; v != "" && v != "none" && (!strings.HasSuffix(v, "%") || b.cbHeight >= 0) {
+		h = math.Min(h, parseLength(v, b.cbHeight, b.st.fontSize, 16))
+	}
+	if v := b.st.minHeight; v != "" && v != "auto" && (!strings.HasSuffix(v, "%") || b.cbHeight >= 0) {
+		h = math.Max(h, parseLength(v, b.cbHeight, b.st.fontSize, 16))
+	}
+	return h
+}
+
+// layout positions b at (x, y) with border-box width w. forcedH >= 0 fixes the height
+// (used for stretched flex/grid items); cbHeight is the containing block height.
+func (b *box) layout(x, y, w, forcedH, cbHeight float64) {
+	b.x, b.y, b.w = x, y, w
+	b.cbHeight = cbHeight
+
+	definiteH := -1.0
+	if forcedH >= 0 {
+		definiteH = forcedH
+	} else if h, ok := b.specifiedHeight(); ok {
+		definiteH = b.clampHeight(h)
+	}
+
+	innerX := x + b.edges(3)
+	innerY := y + b.edges(0)
+	innerW := math.Max(0, w-b.horizontalEdges())
+	innerH := -1.0
+	if definiteH >= 0 {
+		innerH = math.Max(0, definiteH-b.verticalEdges())
+	}
+
+	var contentH float64
+	switch b.st.display {
+	case displayFlex:
+		if b.st.flexDirection == "column" || b.st.flexDirection == "column-reverse" {
+			contentH = b.layoutFlexColumn(innerX, innerY, innerW, innerH)
+		} else {
+			contentH = b.layoutFlexRow(innerX, innerY, innerW, innerH)
+		}
+	case displayGrid:
+		contentH = b.layoutGrid(innerX, innerY, innerW, innerH)
+	case displayInline:
+		contentH = b.layoutLines(innerX, innerY, innerW)
+	default:
+		contentH = b.layoutBlock(innerX, innerY, innerW, innerH)
+	}
+
+	if definiteH >= 0 {
+		b.h = definiteH
+	} else {
+		b.h = b.clampHeight(contentH + b.verticalEdges())
+	}
+}
+
+func (b *box) layoutBlock(x, y, w, h float64) float64 {
+	cursor := y
+	for _, c := range b.children {
+		cw := w - c.st.margin[1] - c.st.margin[3]
+		if sw, ok := c.specifiedWidth(w); ok {
+			cw = sw
+		}
+		cursor += c.st.margin[0]
+		c.layout(x+c.st.margin[3], cursor, cw, -1, h)
+		cursor += c.h + c.st.margin[2]
+	}
+	return cursor - y
+}
+
+// maxContentWidth is the border-box width the box would take without wrapping
+func (b *box) maxContentWidth(containerW float64) float64 {
+	if w, ok := b.specifiedWidth(containerW); ok {
+		return w
+	}
+	content := 0.0
+	switch b.st.display {
+	case displayInline:
+		lineW := 0.0
+		pendingSpace := false
+		for _, word := range splitWords(b.runs) {
+			if word.br {
+				content = math.Max(content, lineW)
+				lineW = 0
+				pendingSpace = false
+				continue
+			}
+			if word.spaceBefore && lineW > 0 || pendingSpace && lineW > 0 {
+				lineW += measureText(" ", word.style)
+			}
+			lineW += measureText(word.text, word.style)
+			pendingSpace = false
+		}
+		content = math.Max(content, lineW)
+	case displayFlex:
+		if b.st.flexDirection == "column" || b.st.flexDirection == "column-reverse" {
+			for _, c := range b.children {
+				content = math.Max(content, c.maxContentWidth(containerW)+c.st.margin[1]+c.st.margin[3])
+			}
+		} else {
+			for i, c := range b.children {
+				if i > 0 {
+					content += b.st.columnGap
+				}
+				content += c.maxContentWidth(containerW) + c.st.margin[1] + c.st.margin[3]
+			}
+		}
+	default:
+		for _, c := range b.children {
+			content = math.Max(content, c.maxContentWidth(containerW)+c.st.margin[1]+c.st.margin[3])
+		}
+	}
+	return content + b.horizontalEdges()
+}
+
+func (b *box) alignFor(c *box) string {
+	if c.st.alignSelf != "" && c.st.alignSelf != "auto" {
+		return c.st.alignSelf
+	}
+	if b.st.alignItems == "" || b.st.alignItems == "normal" {
+		return "stretch"
+	}
+	return b.st.alignItems
+}
+
+// justifyOffsets returns the leading offset and extra spacing between items
+func justifyOffsets(justify string, free float64, count int) (float64, float64) {
+	if free <= 0 || count == 0 {
+		return 0, 0
+	}
+	switch justify {
+	case "center":
+		return free / 2, 0
+	case "flex-end", "end", "right":
+		return free, 0
+	case "space-between":
+		if count == 1 {
+			return 0, 0
+		}
+		return 0, free / float64(count-1)
+	case "space-around":
+		return free / float64(count) / 2, free / float64(count)
+	case "space-evenly":
+		return free / float64(count+1), free / float64(count+1)
+	}
+	return 0, 0
+}
+
+func (b *box) flexBase(c *box, mainSize float64, column bool) (float64, bool) {
+	basis := c.st.flexBasis
+	if basis != "" && basis != "auto" && basis != "content" {
+		if mainSize < 0 && strings.HasSuffix(basis, "%") {
+			return 0, false
+		}
+		return parseLength(basis, mainSize, c.st.fontSize, 16), true
+	}
+	if column {
+		if h, ok := c.specifiedHeight(); ok {
+			return h, true
+		}
+		return 0, false
+	}
+	if w, ok := c.specifiedWidth(mainSize); ok {
+		return w, true
+	}
+	return 0, false
+}
+
+func (b *box) layoutFlexRow(x, y, w, h float64) float64 {
+	n := len(b.children)
+	if n == 0 {
+		return 0
+	}
+
+	widths := make([]float64, n)
+	total := b.st.columnGap * float64(n-1)
+	for i, c := range b.children {
+		if base, ok := b.flexBase(c, w, false); ok {
+			widths[i] = base
+		} else {
+			widths[i] = math.Min(c.maxContentWidth(w), w)
+		}
+		total += widths[i] + c.st.margin[1] + c.st.margin[3]
+	}
+
+	free := w - total
+	if free > 0 {
+		grow := 0.0
+		for _, c := range b.children {
+			grow += c.st.flexGrow
+		}
+		if grow > 0 {
+			for i, c := range b.children {
+				widths[i] += free * c.st.flexGrow / grow
+			}
+			free = 0
+		}
+	} else if free < 0 {
+		weight := 0.0
+		for i, c := range b.children {
+			weight += c.st.flexShrink * widths[i]
+		}
+		if weight > 0 {
+			overflow := -free
+			for i, c := range b.children {
+				widths[i] = math.Max(0, widths[i]-overflow*c.st.flexShrink*widths[i]/weight)
+			}
+		}
+		free = 0
+	}
+
+	offset, spacing := justifyOffsets(b.st.justifyContent, free, n)
+	positions := make([]float64, n)
+	cursor := x + offset
+	for i, c := range b.children {
+		cursor += c.st.margin[3]
+		positions[i] = cursor
+		cursor += widths[i] + c.st.margin[1] + b.st.columnGap + spacing
+	}
+
+	// First pass at natural height to find the line's cross size
+	cross := 0.0
+	for i, c := range b.children {
+		c.layout(positions[i], y+c.st.margin[0], widths[i], -1, h)
+		cross = math.Max(cross, c.h+c.st.margin[0]+c.st.margin[2])
+	}
+	if h >= 0 {
+		cross = h
+	}
+
+	for i, c := range b.children {
+		avail := cross - c.st.margin[0] - c.st.margin[2]
+		switch b.alignFor(c) {
+		case "stretch":
+			if _, ok := c.specifiedHeight(); !ok {
+				c.layout(positions[i], y+c.st.margin[0], widths[i], c.clampHeight(avail), h)
+			}
+		case "center":
+			c.layout(positions[i], y+c.st.margin[0]+(avail-c.h)/2, widths[i], -1, h)
+		case "flex-end", "end", "baseline", "last baseline":
+			c.layout(positions[i], y+c.st.margin[0]+avail-c.h, widths[i], -1, h)
+		}
+	}
+
+	return cross
+}
+
+func (b *box) layoutFlexColumn(x, y, w, h float64) float64 {
+	n := len(b.children)
+	if n == 0 {
+		return 0
+	}
+
+	widthFor := func(c *box) (float64, float64) {
+		cw := w - c.st.margin[1] - c.st.margin[3]
+		if sw, ok := c.specifiedWidth(w); ok {
+			return sw, 0
+		}
+		align := b.alignFor(c)
+		if align == "stretch" {
+			return cw, 0
+		}
+		natural := math.Min(c.maxContentWidth(w), cw)
+		switch align {
+		case "center":
+			return natural, (cw - natural) / 2
+		case "flex-end", "end":
+			return natural, cw - natural
+		}
+		return natural, 0
+	}
+
+	heights := make([]float64, n)
+	widths := make([]float64, n)
+	offsets := make([]float64, n)
+	total := b.st.rowGap * float64(n-1)
+	for i, c := range b.children {
+		widths[i], offsets[i] = widthFor(c)
+		if base, ok := b.flexBase(c, h, true); ok {
+			heights[i] = base
+		} else {
+			c.layout(x, y, widths[i], -1, h)
+			heights[i] = c.h
+		}
+		total += heights[i] + c.st.margin[0] + c.st.margin[2]
+	}
+
+	free := 0.0
+	if h >= 0 {
+		free = h - total
+		if free > 0 {
+			grow := 0.0
+			for _, c := range b.children {
+				grow += c.st.flexGrow
+			}
+			if grow > 0 {
+				for i, c := range b.children {
+					heights[i] += free * c.st.flexGrow / grow
+				}
+				free = 0
+			}
+		} else if free < 0 {
+			weight := 0.0
+			for i, c := range b.children {
+				weight += c.st.flexShrink * heights[i]
+			}
+			if weight > 0 {
+				overflow := -free
+				for i, c := range b.children {
+					heights[i] = math.Max(0, heights[i]-overflow*c.st.flexShrink*heights[i]/weight)
+				}
+			}
+			free = 0
+		}
+	}
+
+	start, spacing := justifyOffsets(b.st.justifyContent, free, n)
+	cursor := y + start
+	for i, c := range b.children {
+		cursor += c.st.margin[0]
+		c.layout(x+c.st.margin[3]+offsets[i], cursor, widths[i], c.clampHeight(heights[i]), h)
+		cursor += c.h + c.st.margin[2] + b.st.rowGap + spacing
+	}
+
+	if h >= 0 {
+		return h
+	}
+	return cursor - y - b.st.rowGap - spacing
+}
+
+// gridTracks parses grid-template-columns into pixel widths for the available width
+func gridTracks(spec string, w, gap float64) []float64 {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || spec == "none" {
+		return []float64{w}
+	}
+
+	// Expand repeat(N, track)
+	tokens := []string{}
+	for spec != "" {
+		if strings.HasPrefix(spec, "repeat(") {
+			end := strings.IndexByte(spec, ')')
+			if end == -1 {
+				break
+			}
+			args := strings.SplitN(spec[len("repeat("):end], ",", 2)
+			count, err := strconv.Atoi(strings.TrimSpace(args[0]))
+			if err == nil && len(args) == 2 {
+				for i := 0; i < count; i++ {
+					tokens = append(tokens, strings.Fields(args[1])...)
+				}
+			}
+			spec = strings.TrimSpace(spec[end+1:])
+			continue
+		}
+		fields := strings.SplitN(spec, " ", 2)
+		tokens = append(tokens, fields[0])
+		if len(fields) == 1 {
+			break
+		}
+		spec = strings.TrimSpace(fields[1])
+	}
+	if len(tokens) == 0 {
+		return []float64{w}
+	}
+
+	tracks := make([]float64, len(tokens))
+	remaining := w - gap*float64(len(tokens)-1)
+	fr := 0.0
+	for i, t := range tokens {
+		switch {
+		case strings.HasSuffix(t, "fr"):
+			fr += parseFloatDefault(strings.TrimSuffix(t, "fr"), 1)
+		case t == "auto" || strings.HasPrefix(t, "minmax("):
+			fr++
+		default:
+			tracks[i] = parseLength(t, w, 16, 16)
+			remaining -= tracks[i]
+		}
+	}
+	if fr > 0 {
+		for i, t := range tokens {
+			switch {
+			case strings.HasSuffix(t, "fr"):
+				tracks[i] = math.Max(0, remaining) * parseFloatDefault(strings.TrimSuffix(t, "fr"), 1) / fr
+			case t == "auto" || strings.HasPrefix(t, "minmax("):
+				tracks[i] = math.Max(0, remaining) / fr
+			}
+		}
+	}
+	return tracks
+}
+
+func (b *box) layoutGrid(x, y, w, h float64) float64 {
+	if len(b.children) == 0 {
+		return 0
+	}
+
+	tracks := gridTracks(b.st.gridColumns, w, b.st.columnGap)
+	cols := len(tracks)
+
+	type placement struct {
+		row, col, span int
+	}
+	placements := make([]placement, len(b.children))
+	row, col := 0, 0
+	for i, c := range b.children {
+		span := c.st.gridSpan
+		if span > cols {
+			span = cols
+		}
+		if col+span > cols {
+			row++
+			col = 0
+		}
+		placements[i] = placement{row: row, col: col, span: span}
+		col += span
+	}
+	rows := row + 1
+
+	colX := make([]float64, cols)
+	cursor := x
+	for i, t := range tracks {
+		colX[i] = cursor
+		cursor += t + b.st.columnGap
+	}
+	spanWidth := func(p placement) float64 {
+		width := b.st.columnGap * float64(p.span-1)
+		for i := p.col; i < p.col+p.span; i++ {
+			width += tracks[i]
+		}
+		return width
+	}
+
+	// Auto rows size to their tallest item, then stretch to fill a definite container
+	rowHeights := make([]float64, rows)
+	for i, c := range b.children {
+		p := placements[i]
+		c.layout(colX[p.col]+c.st.margin[3], y, spanWidth(p)-c.st.margin[1]-c.st.margin[3], -1, -1)
+		rowHeights[p.row] = math.Max(rowHeights[p.row], c.h+c.st.margin[0]+c.st.margin[2])
+	}
+	total := b.st.rowGap * float64(rows-1)
+	for _, rh := range rowHeights {
+		total += rh
+	}
+	if h >= 0 && total < h {
+		extra := (h - total) / float64(rows)
+		for i := range rowHeights {
+			rowHeights[i] += extra
+		}
+		total = h
+	}
+
+	rowY := make([]float64, rows)
+	cursor = y
+	for i, rh := range rowHeights {
+		rowY[i] = cursor
+		cursor += rh + b.st.rowGap
+	}
+
+	for i, c := range b.children {
+		p := placements[i]
+		avail := rowHeights[p.row] - c.st.margin[0] - c.st.margin[2]
+		cx := colX[p.col] + c.st.margin[3]
+		cw := spanWidth(p) - c.st.margin[1] - c.st.margin[3]
+		if sw, ok := c.specifiedWidth(cw); ok {
+			cw = sw
+		}
+		cy := rowY[p.row] + c.st.margin[0]
+		switch b.alignFor(c) {
+		case "stretch":
+			if _, ok := c.specifiedHeight(); !ok {
+				c.layout(cx, cy, cw, c.clampHeight(avail), rowHeights[p.row])
+				continue
+			}
+			c.layout(cx, cy, cw, -1, rowHeights[p.row])
+		case "center":
+			c.layout(cx, cy, cw, -1, rowHeights[p.row])
+			c.layout(cx, cy+(avail-c.h)/2, cw, -1, rowHeights[p.row])
+		case "end", "flex-end":
+			c.layout(cx, cy, cw, -1, rowHeights[p.row])
+			c.layout(cx, cy+avail-c.h, cw, -1, rowHeights[p.row])
+		default:
+			c.layout(cx, cy, cw, -1, rowHeights[p.row])
+		}
+	}
+
+	return total
+}
+
+type textWord struct {
+	text        string
+	style       *boxStyle
+	spaceBefore bool
+	br          bool
+}
+
+// splitWords collapses whitespace and splits runs into words, remembering spaces between them
+func splitWords(runs []textRun) []textWord {
+	words := []textWord{}
+	space := false
+	for _, run := range runs {
+		if run.br {
+			words = append(words, textWord{br: true, style: run.style})
+			space = false
+			continue
+		}
+		text := run.text
+		switch run.style.textTransform {
+		case "uppercase":
+			text = strings.ToUpper(text)
+		case "lowercase":
+			text = strings.ToLower(text)
+		}
+		start := -1
+		for i, r := range text {
+			if unicode.IsSpace(r) {
+				if start >= 0 {
+					words = append(words, textWord{text: text[start:i], style: run.style, spaceBefore: space})
+					start = -1
+				}
+				space = true
+				continue
+			}
+			if start < 0 {
+				start = i
+			}
+		}
+		if start >= 0 {
+			words = append(words, textWord{text: text[start:], style: run.style, spaceBefore: space})
+			space = false
+		}
+	}
+	return words
+}
+
+func (b *box) layoutLines(x, y, w float64) float64 {
+	b.lines = nil
+	words := splitWords(b.runs)
+	if len(words) == 0 {
+		return 0
+	}
+
+	var current textLine
+	lineW := 0.0
+	flush := func() {
+		b.lines = append(b.lines, current)
+		current = textLine{}
+		lineW = 0
+	}
+
+	for _, word := range words {
+		if word.br {
+			flush()
+			continue
+		}
+		wordW := measureText(word.text, word.style)
+		spaceW := 0.0
+		if word.spaceBefore && len(current.fragments) > 0 {
+			spaceW = measureText(" ", word.style)
+		}
+		if !b.st.nowrap && len(current.fragments) > 0 && lineW+spaceW+wordW > w {
+			flush()
+			spaceW = 0
+		}
+		// Merge with the previous fragment when the style is unchanged
+		if n := len(current.fragments); n > 0 && current.fragments[n-1].style == word.style {
+			frag := &current.fragments[n-1]
+			if spaceW > 0 {
+				frag.text += " "
+			}
+			frag.text += word.text
+			frag.width += spaceW + wordW
+		} else {
+			text := word.text
+			if spaceW > 0 {
+				text = " " + text
+			}
+			current.fragments = append(current.fragments, textFragment{text: text, style: word.style, width: spaceW + wordW})
+		}
+		lineW += spaceW + wordW
+	}
+	if len(current.fragments) > 0 {
+		flush()
+	}
+
+	cursor := y
+	for i := range b.lines {
+		line := &b.lines[i]
+
+		if b.st.ellipsis && b.st.nowrap {
+			truncateLine(line, w)
+		}
+
+		line.height = b.st.lineHeight
+		ascent := 0.0
+		for _, frag := range line.fragments {
+			line.height = math.Max(line.height, frag.style.lineHeight)
+		}
+		for _, frag := range line.fragments {
+			metrics := fontFace(frag.style).Metrics()
+			asc := float64(metrics.Ascent.Ceil())
+			desc := float64(metrics.Descent.Ceil())
+			ascent = math.Max(ascent, asc+(frag.style.lineHeight-(asc+desc))/2)
+		}
+		line.y = cursor
+		line.baseline = cursor + ascent + (line.height-maxFragmentLineHeight(line))/2
+
+		total := 0.0
+		for _, frag := range line.fragments {
+			total += frag.width
+		}
+		offset := 0.0
+		switch b.st.textAlign {
+		case "center":
+			offset = math.Max(0, (w-total)/2)
+		case "right", "end":
+			offset = math.Max(0, w-total)
+		}
+		fx := x + offset
+		for j := range line.fragments {
+			line.fragments[j].x = fx
+			fx += line.fragments[j].width
+		}
+
+		cursor += line.height
+	}
+
+	return cursor - y
+}
+
+func maxFragmentLineHeight(line *textLine) float64 {
+	h := 0.0
+	for _, frag := range line.fragments {
+		h = math.Max(h, frag.style.lineHeight)
+	}
+	return h
+}
+
+// truncateLine shortens an overflowing line and appends an ellipsis
+func truncateLine(line *textLine, w float64) {
+	total := 0.0
+	for _, frag := range line.fragments {
+		total += frag.width
+	}
+	if total <= w {
+		return
+	}
+
+	used := 0.0
+	for i := range line.fragments {
+		frag := &line.fragments[i]
+		ellipsisW := measureText("…", frag.style)
+		if used+frag.width+ellipsisW <= w && i < len(line.fragments)-1 {
+			used += frag.width
+			continue
+		}
+		runes := []rune(frag.text)
+		for len(runes) > 0 && used+measureText(string(runes)+"…", frag.style) > w {
+			runes = runes[:len(runes)-1]
+		}
+		frag.text = strings.TrimRight(string(runes), " ") + "…"
+		frag.width = measureText(frag.text, frag.style)
+		line.fragments = line.fragments[:i+1]
+		return
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Painting
+
+func (b *box) paint(canvas *image.RGBA, clip image.Rectangle) {
+	if b.st.hidden {
+		return
+	}
+
+	if b.st.hasBackground {
+		fillRect(canvas, clip, b.x, b.y, b.w, b.h, blendOpacity(b.st.background, b.st.opacity))
+	}
+
+	// Borders: top, right, bottom, left
+	bw := b.st.border
+	if bw[0] > 0 {
+		fillRect(canvas, clip, b.x, b.y, b.w, bw[0], blendOpacity(b.st.borderColor[0], b.st.opacity))
+	}
+	if bw[1] > 0 {
+		fillRect(canvas, clip, b.x+b.w-bw[1], b.y, bw[1], b.h, blendOpacity(b.st.borderColor[1], b.st.opacity))
+	}
+	if bw[2] > 0 {
+		fillRect(canvas, clip, b.x, b.y+b.h-bw[2], b.w, bw[2], blendOpacity(b.st.borderColor[2], b.st.opacity))
+	}
+	if bw[3] > 0 {
+		fillRect(canvas, clip, b.x, b.y, bw[3], b.h, blendOpacity(b.st.borderColor[3], b.st.opacity))
+	}
+
+	childClip := clip
+	if b.st.overflowHidden {
+		inner := image.Rect(
+			int(math.Round(b.x+bw[3])), int(math.Round(b.y+bw[0])),
+			int(math.Round(b.x+b.w-bw[1])), int(math.Round(b.y+b.h-bw[2])))
+		childClip = clip.Intersect(inner)
+	}
+
+	for _, line := range b.lines {
+		for _, frag := range line.fragments {
+			drawText(canvas, childClip, frag, line.baseline)
+		}
+	}
+
+	for _, c := range b.children {
+		c.paint(canvas, childClip)
+	}
+}
+
+func fillRect(canvas *image.RGBA, clip image.Rectangle, x, y, w, h float64, c color.RGBA) {
+	r := image.Rect(int(math.Round(x)), int(math.Round(y)), int(math.Round(x+w)), int(math.Round(y+h))).Intersect(clip)
+	if r.Empty() {
+		return
+	}
+	draw.Draw(canvas, r, image.NewUniform(c), image.Point{}, draw.Over)
+}
+
+func drawText(canvas *image.RGBA, clip image.Rectangle, frag textFragment, baseline float64) {
+	if clip.Empty() {
+		return
+	}
+	st := frag.style
+	face := fontFace(st)
+	col := blendOpacity(st.color, st.opacity)
+	dst := canvas.SubImage(clip).(*image.RGBA)
+
+	d := &font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(col),
+		Face: face,
+		Dot:  fixed.Point26_6{X: fixed.Int26_6(frag.x * 64), Y: fixed.I(int(math.Round(baseline)))},
+	}
+	for _, r := range frag.text {
+		r = glyphFallback(face, r)
+		d.DrawString(string(r))
+		d.Dot.X += fixed.Int26_6(st.letterSpacing * 64)
+	}
+
+	if st.lineThrough {
+		thickness := math.Max(1, math.Round(st.fontSize/14))
+		ascent := float64(face.Metrics().Ascent.Ceil())
+		fillRect(canvas, clip, frag.x, baseline-ascent*0.35-thickness/2, frag.width, thickness, col)
+	}
+}
+
+// blendOpacity mixes a color toward the white page background
+func blendOpacity(c color.RGBA, opacity float64) color.RGBA {
+	if opacity >= 1 {
+		return c
+	}
+	mix := func(v uint8) uint8 {
+		return uint8(math.Round(float64(v)*opacity + 255*(1-opacity)))
+	}
+	return color.RGBA{mix(c.R), mix(c.G), mix(c.B), 255}
+}
+
+// ---------------------------------------------------------------------------
+// Fonts
+
+var (
+	goFontsOnce sync.Once
+	goFonts     map[int]*opentype.Font // Keyed by weight: 400, 500, 700
+	goFontsErr  error
+	faceCacheMu sync.Mutex
+	faceCache   = map[string]font.Face{}
+)
+
+func loadGoFonts() {
+	goFonts = make(map[int]*opentype.Font)
+	for weight, data := range map[int][]byte{400: goregular.TTF, 500: gomedium.TTF, 700: gobold.TTF} {
+		f, err := opentype.Parse(data)
+		if err != nil {
+			goFontsErr = err
+			return
+		}
+		goFonts[weight] = f
+	}
+}
+
+// fontFace returns a cached face for the style's weight and size
+func fontFace(st *boxStyle) font.Face {
+	goFontsOnce.Do(loadGoFonts)
+
+	weight := 400
+	switch {
+	case st.fontWeight >= 600:
+		weight = 700
+	case st.fontWeight >= 500:
+		weight = 500
+	}
+	size := math.Max(1, math.Round(st.fontSize*2)/2)
+	key := fmt.Sprintf("%d/%g", weight, size)
+
+	faceCacheMu.Lock()
+	defer faceCacheMu.Unlock()
+
+	if face, ok := faceCache[key]; ok {
+		return face
+	}
+	if goFontsErr != nil {
+		panic(fmt.Sprintf("embedded Go fonts failed to load: %v", goFontsErr))
+	}
+	face, err := opentype.NewFace(goFonts[weight], &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72, // 1pt == 1px
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		panic(fmt.Sprintf("failed to create font face: %v", err))
+	}
+	faceCache[key] = face
+	return face
+}
+
+// glyphSubstitutes covers symbols used by the templates that the Go fonts don't include
+var glyphSubstitutes = map[rune]rune{
+	'✓': '√',
+	'✔': '√',
+	'✗': '×',
+	'✘': '×',
+}
+
+func glyphFallback(face font.Face, r rune) rune {
+	if sub, ok := glyphSubstitutes[r]; ok {
+		return sub
+	}
+	return r
+}
+
+func measureText(text string, st *boxStyle) float64 {
+	face := fontFace(st)
+	width := 0.0
+	for _, r := range text {
+		adv, ok := face.GlyphAdvance(glyphFallback(face, r))
+		if !ok {
+			continue
+		}
+		width += float64(adv)/64 + st.letterSpacing
+	}
+	return width
+}
+
+// ---------------------------------------------------------------------------
+// CSS value parsing
+
+// parseLength converts a CSS length to pixels. percentBase resolves %, fontSize resolves em.
+func parseLength(v string, percentBase, fontSize, rootFontSize float64) float64 {
+	v = strings.TrimSpace(strings.ToLower(v))
+	switch {
+	case v == "" || v == "auto" || v == "none" || v == "normal":
+		return 0
+	case strings.HasSuffix(v, "px"):
+		return parseFloatDefault(strings.TrimSuffix(v, "px"), 0)
+	case strings.HasSuffix(v, "rem"):
+		return parseFloatDefault(strings.TrimSuffix(v, "rem"), 0) * rootFontSize
+	case strings.HasSuffix(v, "em"):
+		return parseFloatDefault(strings.TrimSuffix(v, "em"), 0) * fontSize
+	case strings.HasSuffix(v, "%"):
+		return parseFloatDefault(strings.TrimSuffix(v, "%"), 0) * percentBase / 100
+	case strings.HasSuffix(v, "pt"):
+		return parseFloatDefault(strings.TrimSuffix(v, "pt"), 0) * 96 / 72
+	case strings.HasPrefix(v, "calc("):
+		return 0
+	}
+	return parseFloatDefault(v, 0)
+}
+
+var fontSizeKeywords = map[string]float64{
+	"xx-small": 9, "x-small": 10, "small": 13, "medium": 16,
+	"large": 18, "x-large": 24, "xx-large": 32,
+}
+
+func resolveFontSize(v string, parentSize float64) float64 {
+	if size, ok := fontSizeKeywords[v]; ok {
+		return size
+	}
+	switch v {
+	case "smaller":
+		return parentSize / 1.2
+	case "larger":
+		return parentSize * 1.2
+	}
+	return parseLength(v, parentSize, parentSize, 16)
+}
+
+func formatPx(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64) + "px"
+}
+
+func parseFloatDefault(v string, def float64) float64 {
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil {
+		return def
+	}
+	return f
+}
+
+func parseFontWeight(v string) int {
+	switch v {
+	case "", "normal":
+		return 400
+	case "bold", "bolder":
+		return 700
+	case "lighter":
+		return 300
+	}
+	if n, err := strconv.Atoi(v); err == nil {
+		return n
+	}
+	return 400
+}
+
+var namedColors = map[string]color.RGBA{
+	"black":       {0, 0, 0, 255},
+	"white":       {255, 255, 255, 255},
+	"gray":        {128, 128, 128, 255},
+	"grey":        {128, 128, 128, 255},
+	"silver":      {192, 192, 192, 255},
+	"lightgray":   {211, 211, 211, 255},
+	"darkgray":    {169, 169, 169, 255},
+	"red":         {255, 0, 0, 255},
+	"transparent": {0, 0, 0, 0},
+}
+
+// parseCSSColor understands #rgb, #rrggbb, rgb()/rgba() and a few named colors
+func parseCSSColor(v string) (color.RGBA, bool) {
+	v = strings.TrimSpace(strings.ToLower(v))
+	if c, ok := namedColors[v]; ok {
+		return c, true
+	}
+	if strings.HasPrefix(v, "#") {
+		hex := v[1:]
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		if len(hex) != 6 {
+			return color.RGBA{}, false
+		}
+		n, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return color.RGBA{}, false
+		}
+		return color.RGBA{uint8(n >> 16), uint8(n >> 8), uint8(n), 255}, true
+	}
+	if strings.HasPrefix(v, "rgb") {
+		open := strings.IndexByte(v, '(')
+		end := strings.IndexByte(v, ')')
+		if open == -1 || end < open {
+			return color.RGBA{}, false
+		}
+		parts := strings.FieldsFunc(v[open+1:end], func(r rune) bool { return r == ',' || r == ' ' || r == '/' })
+		if len(parts) < 3 {
+			return color.RGBA{}, false
+		}
+		c := color.RGBA{A: 255}
+		c.R = uint8(parseFloatDefault(parts[0], 0))
+		c.G = uint8(parseFloatDefault(parts[1], 0))
+		c.B = uint8(parseFloatDefault(parts[2], 0))
+		if len(parts) == 4 {
+			c.A = uint8(math.Round(parseFloatDefault(parts[3], 1) * 255))
+		}
+		return c, true
+	}
+	return color.RGBA{}, false
+}
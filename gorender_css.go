@@ -0,0 +1,598 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Minimal CSS support for the pure-Go renderer: type/class/id selectors, descendant and
+// child combinators, :first-child/:last-child, ::before/::after content and the
+// properties used by the bundled templates.
+
+type cssDecl struct {
+	prop      string
+	value     string
+	important bool
+}
+
+type cssCompound struct {
+	tag           string
+	id            string
+	classes       []string
+	pseudoClasses []string
+	pseudoElement string
+	combinator    byte // Combinator to the compound on the left: ' ' (descendant) or '>' (child)
+}
+
+type cssRule struct {
+	selector    []cssCompound
+	specificity int
+	order       int
+	decls       []cssDecl
+}
+
+var cssCommentPattern = regexp.MustCompile(`(?s)/\*.*?\*/`)
+
+// inheritedProps are copied from the parent when an element doesn't set them
+var inheritedProps = map[string]bool{
+	"color":           true,
+	"font-size":       true,
+	"font-weight":     true,
+	"font-family":     true,
+	"font-style":      true,
+	"line-height":     true,
+	"letter-spacing":  true,
+	"text-transform":  true,
+	"text-align":      true,
+	"white-space":     true,
+	"visibility":      true,
+	"text-decoration": true, // Not inherited in CSS, but decorations propagate to descendants
+}
+
+// uaDisplay is the default display value for elements not styled by the page
+var uaDisplay = map[string]string{
+	"html": "block", "body": "block", "div": "block", "p": "block", "section": "block",
+	"article": "block", "header": "block", "footer": "block", "main": "block", "nav": "block",
+	"aside": "block", "h1": "block", "h2": "block", "h3": "block", "h4": "block", "h5": "block",
+	"h6": "block", "ul": "block", "ol": "block", "li": "block", "table": "block", "tr": "flex",
+	"td": "block", "th": "block", "tbody": "block", "thead": "block", "figure": "block",
+	"head": "none", "style": "none", "script": "none", "title": "none", "meta": "none",
+	"link": "none", "template": "none",
+}
+
+// uaStyles are user-agent defaults applied before page rules
+var uaStyles = map[string][]cssDecl{
+	"body":   {{prop: "margin", value: "8px"}},
+	"h1":     {{prop: "font-size", value: "2em"}, {prop: "font-weight", value: "bold"}},
+	"h2":     {{prop: "font-size", value: "1.5em"}, {prop: "font-weight", value: "bold"}},
+	"h3":     {{prop: "font-size", value: "1.17em"}, {prop: "font-weight", value: "bold"}},
+	"h4":     {{prop: "font-weight", value: "bold"}},
+	"b":      {{prop: "font-weight", value: "bold"}},
+	"strong": {{prop: "font-weight", value: "bold"}},
+	"th":     {{prop: "font-weight", value: "bold"}},
+	"s":      {{prop: "text-decoration", value: "line-through"}},
+	"del":    {{prop: "text-decoration", value: "line-through"}},
+}
+
+// parseStylesheet parses CSS source into rules, skipping at-rules
+func parseStylesheet(src string, orderOffset int) []cssRule {
+	src = cssCommentPattern.ReplaceAllString(src, "")
+	rules := []cssRule{}
+	order := orderOffset
+
+	for {
+		open := strings.IndexByte(src, '{')
+		if open == -1 {
+			break
+		}
+		prelude := strings.TrimSpace(src[:open])
+
+		// Find the matching closing brace (at-rules like @media contain nested blocks)
+		depth := 0
+		end := -1
+		for i := open; i < len(src); i++ {
+			if src[i] == '{' {
+				depth++
+			} else if src[i] == '}' {
+				depth--
+				if depth == 0 {
+					end = i
+					break
+				}
+			}
+		}
+		if end == -1 {
+			break
+		}
+		body := src[open+1 : end]
+		src = src[end+1:]
+
+		if strings.HasPrefix(prelude, "@") {
+			continue
+		}
+
+		decls := parseDeclarations(body)
+		for _, selectorText := range strings.Split(prelude, ",") {
+			selector, specificity, ok := parseSelector(strings.TrimSpace(selectorText))
+			if !ok {
+				continue
+			}
+			rules = append(rules, cssRule{selector: selector, specificity: specificity, order: order, decls: decls})
+			order++
+		}
+	}
+
+	return rules
+}
+
+// parseDeclarations parses "prop: value; ..." and expands shorthands into longhands
+func parseDeclarations(body string) []cssDecl {
+	decls := []cssDecl{}
+	for _, part := range strings.Split(body, ";") {
+		colon := strings.IndexByte(part, ':')
+		if colon == -1 {
+			continue
+		}
+		prop := strings.ToLower(strings.TrimSpace(part[:colon]))
+		value := strings.TrimSpace(part[colon+1:])
+		important := false
+		if idx := strings.Index(strings.ToLower(value), "!important"); idx != -1 {
+			important = true
+			value = strings.TrimSpace(value[:idx])
+		}
+		if prop == "" || value == "" {
+			continue
+		}
+		for _, d := range expandShorthand(prop, value) {
+			d.important = important
+			decls = append(decls, d)
+		}
+	}
+	return decls
+}
+
+var boxSides = []string{"top", "right", "bottom", "left"}
+
+// expandShorthand turns shorthand properties into the longhands the layout engine reads
+func expandShorthand(prop, value string) []cssDecl {
+	switch prop {
+	case "margin", "padding":
+		values := expandFourSides(strings.Fields(value))
+		decls := make([]cssDecl, 4)
+		for i, side := range boxSides {
+			decls[i] = cssDecl{prop: prop + "-" + side, value: values[i]}
+		}
+		return decls
+
+	case "border", "border-top", "border-right", "border-bottom", "border-left":
+		width, color := parseBorderValue(value)
+		sides := boxSides
+		if prop != "border" {
+			sides = []string{strings.TrimPrefix(prop, "border-")}
+		}
+		decls := []cssDecl{}
+		for _, side := range sides {
+			decls = append(decls,
+				cssDecl{prop: "border-" + side + "-width", value: width},
+				cssDecl{prop: "border-" + side + "-color", value: color})
+		}
+		return decls
+
+	case "border-width", "border-color":
+		values := expandFourSides(strings.Fields(value))
+		suffix := strings.TrimPrefix(prop, "border")
+		decls := make([]cssDecl, 4)
+		for i, side := range boxSides {
+			decls[i] = cssDecl{prop: "border-" + side + suffix, value: values[i]}
+		}
+		return decls
+
+	case "background":
+		for _, field := range strings.Fields(value) {
+			if _, ok := parseCSSColor(field); ok {
+				return []cssDecl{{prop: "background-color", value: field}}
+			}
+		}
+		return nil
+
+	case "flex":
+		fields := strings.Fields(value)
+		switch {
+		case value == "none":
+			return []cssDecl{{prop: "flex-grow", value: "0"}, {prop: "flex-shrink", value: "0"}, {prop: "flex-basis", value: "auto"}}
+		case value == "auto":
+			return []cssDecl{{prop: "flex-grow", value: "1"}, {prop: "flex-shrink", value: "1"}, {prop: "flex-basis", value: "auto"}}
+		case len(fields) == 1:
+			return []cssDecl{{prop: "flex-grow", value: fields[0]}, {prop: "flex-shrink", value: "1"}, {prop: "flex-basis", value: "0"}}
+		case len(fields) == 2:
+			return []cssDecl{{prop: "flex-grow", value: fields[0]}, {prop: "flex-shrink", value: fields[1]}, {prop: "flex-basis", value: "0"}}
+		case len(fields) >= 3:
+			return []cssDecl{{prop: "flex-grow", value: fields[0]}, {prop: "flex-shrink", value: fields[1]}, {prop: "flex-basis", value: fields[2]}}
+		}
+		return nil
+
+	case "gap", "grid-gap":
+		fields := strings.Fields(value)
+		if len(fields) == 1 {
+			fields = append(fields, fields[0])
+		}
+		return []cssDecl{{prop: "row-gap", value: fields[0]}, {prop: "column-gap", value: fields[1]}}
+
+	case "grid-column":
+		return []cssDecl{{prop: "grid-column", value: strings.TrimSpace(strings.SplitN(value, "/", 2)[0])}}
+	}
+
+	return []cssDecl{{prop: prop, value: value}}
+}
+
+func expandFourSides(values []string) []string {
+	switch len(values) {
+	case 1:
+		return []string{values[0], values[0], values[0], values[0]}
+	case 2:
+		return []string{values[0], values[1], values[0], values[1]}
+	case 3:
+		return []string{values[0], values[1], values[2], values[1]}
+	case 4:
+		return values
+	}
+	return []string{"0", "0", "0", "0"}
+}
+
+// parseBorderValue extracts width and color from a border shorthand like "2px solid #000"
+func parseBorderValue(value string) (string, string) {
+	width, color := "medium", "currentcolor"
+	if value == "none" || value == "0" {
+		return "0", color
+	}
+	hasStyle := false
+	for _, field := range strings.Fields(value) {
+		switch field {
+		case "solid", "dashed", "dotted", "double", "groove", "ridge", "inset", "outset":
+			hasStyle = true
+		case "none", "hidden":
+			return "0", color
+		default:
+			if _, ok := parseCSSColor(field); ok {
+				color = field
+			} else {
+				width = field
+			}
+		}
+	}
+	if !hasStyle {
+		return "0", color
+	}
+	return width, color
+}
+
+// parseSelector parses a single complex selector into compounds (left to right)
+func parseSelector(text string) ([]cssCompound, int, bool) {
+	if text == "" {
+		return nil, 0, false
+	}
+	text = strings.ReplaceAll(text, ">", " > ")
+
+	compounds := []cssCompound{}
+	specificity := 0
+	combinator := byte(' ')
+	for _, token := range strings.Fields(text) {
+		if token == ">" {
+			combinator = '>'
+			continue
+		}
+		compound, spec, ok := parseCompound(token)
+		if !ok {
+			return nil, 0, false
+		}
+		compound.combinator = combinator
+		combinator = ' '
+		compounds = append(compounds, compound)
+		specificity += spec
+	}
+	if len(compounds) == 0 {
+		return nil, 0, false
+	}
+	return compounds, specificity, true
+}
+
+func parseCompound(token string) (cssCompound, int, bool) {
+	c := cssCompound{}
+	spec := 0
+
+	if idx := strings.Index(token, "::"); idx != -1 {
+		c.pseudoElement = token[idx+2:]
+		token = token[:idx]
+		spec++
+	}
+
+	// Split "tag.a.b#id:first-child" into its simple selectors
+	i := 0
+	readName := func() string {
+		start := i
+		for i < len(token) && token[i] != '.' && token[i] != '#' && token[i] != ':' && token[i] != '[' {
+			i++
+		}
+		return token[start:i]
+	}
+
+	c.tag = strings.ToLower(readName())
+	if c.tag == "*" {
+		c.tag = ""
+	} else if c.tag != "" {
+		spec++
+	}
+	for i < len(token) {
+		switch token[i] {
+		case '.':
+			i++
+			c.classes = append(c.classes, readName())
+			spec += 10
+		case '#':
+			i++
+			c.id = readName()
+			spec += 100
+		case ':':
+			i++
+			name := readName()
+			switch name {
+			case "first-child", "last-child":
+				c.pseudoClasses = append(c.pseudoClasses, name)
+				spec += 10
+			case "before", "after":
+				c.pseudoElement = name
+				spec++
+			default:
+				return c, 0, false // Unsupported pseudo-class (:hover, :nth-child, ...)
+			}
+		default:
+			return c, 0, false // Attribute selectors are not supported
+		}
+	}
+
+	return c, spec, true
+}
+
+// styledNode is a DOM node with its computed (cascaded + inherited) properties
+type styledNode struct {
+	tag      string // Empty for text nodes
+	text     string
+	id       string
+	classes  []string
+	attrs    map[string]string
+	parent   *styledNode
+	children []*styledNode
+	props    map[string]string
+	index    int // Position among element siblings
+	siblings int // Number of element siblings (including this one)
+}
+
+func (n *styledNode) hasClass(class string) bool {
+	for _, c := range n.classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func (c cssCompound) matches(n *styledNode) bool {
+	if n == nil || n.tag == "" {
+		return false
+	}
+	if c.tag != "" && c.tag != n.tag {
+		return false
+	}
+	if c.id != "" && c.id != n.id {
+		return false
+	}
+	for _, class := range c.classes {
+		if !n.hasClass(class) {
+			return false
+		}
+	}
+	for _, pseudo := range c.pseudoClasses {
+		if pseudo == "first-child" && n.index != 0 {
+			return false
+		}
+		if pseudo == "last-child" && n.index != n.siblings-1 {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesSelector checks the compounds right to left against n and its ancestors
+func matchesSelector(selector []cssCompound, n *styledNode) bool {
+	last := len(selector) - 1
+	if !selector[last].matches(n) {
+		return false
+	}
+	return matchAncestors(selector, last, n)
+}
+
+func matchAncestors(selector []cssCompound, idx int, n *styledNode) bool {
+	if idx == 0 {
+		return true
+	}
+	prev := selector[idx-1]
+	if selector[idx].combinator == '>' {
+		if prev.matches(n.parent) {
+			return matchAncestors(selector, idx-1, n.parent)
+		}
+		return false
+	}
+	for a := n.parent; a != nil; a = a.parent {
+		if prev.matches(a) && matchAncestors(selector, idx-1, a) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildStyledTree converts the parsed HTML document into styled nodes rooted at <html>
+func buildStyledTree(doc *html.Node, rules []cssRule) *styledNode {
+	var htmlNode *html.Node
+	for c := doc.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "html" {
+			htmlNode = c
+		}
+	}
+	if htmlNode == nil {
+		return nil
+	}
+	return buildStyledNode(htmlNode, nil, 0, 1, rules)
+}
+
+func buildStyledNode(h *html.Node, parent *styledNode, index, siblings int, rules []cssRule) *styledNode {
+	n := &styledNode{
+		tag:      h.Data,
+		attrs:    make(map[string]string),
+		parent:   parent,
+		index:    index,
+		siblings: siblings,
+	}
+	for _, attr := range h.Attr {
+		n.attrs[attr.Key] = attr.Val
+		switch attr.Key {
+		case "id":
+			n.id = attr.Val
+		case "class":
+			n.classes = strings.Fields(attr.Val)
+		}
+	}
+
+	n.props = computeProps(n, rules, "")
+
+	elementCount := 0
+	for c := h.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			elementCount++
+		}
+	}
+
+	if before := pseudoElementNode(n, rules, "before"); before != nil {
+		n.children = append(n.children, before)
+	}
+	elementIndex := 0
+	for c := h.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.ElementNode:
+			n.children = append(n.children, buildStyledNode(c, n, elementIndex, elementCount, rules))
+			elementIndex++
+		case html.TextNode:
+			n.children = append(n.children, &styledNode{text: c.Data, parent: n, props: n.props})
+		}
+	}
+	if after := pseudoElementNode(n, rules, "after"); after != nil {
+		n.children = append(n.children, after)
+	}
+
+	return n
+}
+
+// pseudoElementNode creates an inline node for ::before/::after content, if any
+func pseudoElementNode(n *styledNode, rules []cssRule, pseudo string) *styledNode {
+	props := computeProps(n, rules, pseudo)
+	content, ok := props["content"]
+	if !ok || content == "none" || content == "normal" {
+		return nil
+	}
+	content = strings.Trim(content, `"'`)
+	if content == "" {
+		return nil
+	}
+	span := &styledNode{tag: "span", parent: n, props: props, attrs: map[string]string{}}
+	span.props["display"] = "inline"
+	span.children = []*styledNode{{text: content, parent: span, props: props}}
+	return span
+}
+
+// computeProps runs the cascade for n (or one of its pseudo-elements) and applies inheritance
+func computeProps(n *styledNode, rules []cssRule, pseudo string) map[string]string {
+	type matched struct {
+		decl        cssDecl
+		specificity int
+		order       int
+	}
+	candidates := []matched{}
+
+	if pseudo == "" {
+		for _, d := range uaStyles[n.tag] {
+			candidates = append(candidates, matched{decl: d, specificity: -1})
+		}
+	}
+	for _, rule := range rules {
+		if rule.selector[len(rule.selector)-1].pseudoElement != pseudo {
+			continue
+		}
+		if matchesSelector(rule.selector, n) {
+			for _, d := range rule.decls {
+				candidates = append(candidates, matched{decl: d, specificity: rule.specificity, order: rule.order})
+			}
+		}
+	}
+	if style, ok := n.attrs["style"]; ok && pseudo == "" {
+		for _, d := range parseDeclarations(style) {
+			candidates = append(candidates, matched{decl: d, specificity: 1000, order: 1 << 30})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.decl.important != b.decl.important {
+			return !a.decl.important
+		}
+		if a.specificity != b.specificity {
+			return a.specificity < b.specificity
+		}
+		return a.order < b.order
+	})
+
+	props := make(map[string]string)
+	for _, c := range candidates {
+		props[c.decl.prop] = c.decl.value
+	}
+
+	var parentProps map[string]string
+	if pseudo != "" {
+		parentProps = n.props
+	} else if n.parent != nil {
+		parentProps = n.parent.props
+	}
+	for prop := range inheritedProps {
+		if v, ok := props[prop]; (!ok || v == "inherit") && parentProps != nil {
+			if pv, ok := parentProps[prop]; ok {
+				props[prop] = pv
+			} else {
+				delete(props, prop)
+			}
+		}
+	}
+
+	// Resolve relative font sizes now so descendants inherit pixels
+	parentSize := 16.0
+	if parentProps != nil {
+		if v, ok := parentProps["font-size"]; ok {
+			parentSize = parseLength(v, 16, 16, 16)
+		}
+	}
+	if v, ok := props["font-size"]; ok {
+		props["font-size"] = formatPx(resolveFontSize(v, parentSize))
+	} else {
+		props["font-size"] = formatPx(parentSize)
+	}
+
+	if _, ok := props["display"]; !ok && pseudo == "" {
+		if d, ok := uaDisplay[n.tag]; ok {
+			props["display"] = d
+		} else {
+			props["display"] = "inline"
+		}
+	}
+
+	return props
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+)
+
+// findBox returns the first box (depth first) whose element has the class
+func findBox(b *box, class string) *box {
+	if b.node != nil {
+		for _, c := range b.node.classes {
+			if c == class {
+				return b
+			}
+		}
+	}
+	for _, child := range b.children {
+		if found := findBox(child, class); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func layoutTestDocument(t *testing.T, body string, width, height int) *box {
+	t.Helper()
+	src := `<!DOCTYPE html><html><head><style>` + baseStyles(width, height) + `</style></head><body>` + body + `</body></html>`
+	root, err := layoutDocument(src, width, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root == nil {
+		t.Fatal("layoutDocument returned no boxes")
+	}
+	return root
+}
+
+func assertBox(t *testing.T, b *box, name string, x, y, w, h float64) {
+	t.Helper()
+	if b == nil {
+		t.Fatalf("%s: box not found", name)
+	}
+	const eps = 0.5
+	if math.Abs(b.x-x) > eps || math.Abs(b.y-y) > eps || math.Abs(b.w-w) > eps || math.Abs(b.h-h) > eps {
+		t.Errorf("%s: box at %.1f,%.1f size %.1fx%.1f, want %.1f,%.1f size %.1fx%.1f", name, b.x, b.y, b.w, b.h, x, y, w, h)
+	}
+}
+
+func TestGoRendererBaseLayout(t *testing.T) {
+	root := layoutTestDocument(t, `<div class="header"><div class="header-title">Title</div></div><div class="content">Body</div>`, 800, 480)
+
+	assertBox(t, findBox(root, "header"), "header", 0, 0, 800, 50)
+	content := findBox(root, "content")
+	if content == nil {
+		t.Fatal("content: box not found")
+	}
+	if content.y < 50 || content.y+content.h > 480 {
+		t.Errorf("content spans y %.1f-%.1f, want within 50-480", content.y, content.y+content.h)
+	}
+}
+
+func TestGoRendererFlexAndGrid(t *testing.T) {
+	root := layoutTestDocument(t, `
+		<div class="row" style="display: flex; width: 600px; height: 100px">
+			<div class="fixed" style="width: 200px"></div>
+			<div class="grow" style="flex: 1"></div>
+		</div>
+		<div class="grid" style="display: grid; grid-template-columns: repeat(3, 1fr); gap: 30px; width: 630px">
+			<div class="cell" style="height: 40px"></div><div></div><div></div>
+		</div>`, 800, 480)
+
+	row := findBox(root, "row")
+	assertBox(t, findBox(root, "fixed"), "fixed", row.x, row.y, 200, 100)
+	assertBox(t, findBox(root, "grow"), "grow", row.x+200, row.y, 400, 100)
+
+	grid := findBox(root, "grid")
+	assertBox(t, findBox(root, "cell"), "cell", grid.x, grid.y, 190, 40)
+}
+
+func TestGoRendererTextWrap(t *testing.T) {
+	const text = "The quick brown fox jumps over the lazy dog again and again"
+	root := layoutTestDocument(t, `
+		<div class="wrap" style="width: 120px; font-size: 16px">`+text+`</div>
+		<div class="clip" style="width: 120px; font-size: 16px; white-space: nowrap; overflow: hidden; text-overflow: ellipsis">`+text+`</div>`, 800, 480)
+
+	wrap := findBox(root, "wrap")
+	if wrap == nil {
+		t.Fatal("wrap: box not found")
+	}
+	if len(wrap.lines) < 3 {
+		t.Fatalf("wrapped into %d lines, want at least 3", len(wrap.lines))
+	}
+	words := []string{}
+	for i, line := range wrap.lines {
+		width := 0.0
+		for _, frag := range line.fragments {
+			width += frag.width
+			words = append(words, strings.Fields(frag.text)...)
+		}
+		if width > 120 {
+			t.Errorf("line %d is %.1fpx wide, want at most 120", i, width)
+		}
+		if i > 0 && line.y <= wrap.lines[i-1].y {
+			t.Errorf("line %d at y=%.1f is not below line %d", i, line.y, i-1)
+		}
+	}
+	if strings.Join(words, " ") != text {
+		t.Errorf("wrapped text = %q, want %q", strings.Join(words, " "), text)
+	}
+	if last := wrap.lines[len(wrap.lines)-1]; math.Abs(wrap.y+wrap.h-(last.y+last.height)) > 0.5 {
+		t.Errorf("box height %.1f does not end at the last line", wrap.h)
+	}
+
+	clip := findBox(root, "clip")
+	if clip == nil {
+		t.Fatal("clip: box not found")
+	}
+	if len(clip.lines) != 1 {
+		t.Fatalf("nowrap text has %d lines, want 1", len(clip.lines))
+	}
+	line := clip.lines[0]
+	last := line.fragments[len(line.fragments)-1].text
+	if !strings.HasSuffix(last, "…") {
+		t.Errorf("truncated line ends with %q, want an ellipsis", last)
+	}
+}
+
+// TestGoRendererTemplates renders the bundled templates with their sample data
+func TestGoRendererTemplates(t *testing.T) {
+	useTestConfig(t)
+
+	for _, view := range defaultViews() {
+		t.Run(view.Name, func(t *testing.T) {
+			viewData, err := loadViewData(view)
+			if err != nil {
+				t.Fatal(err)
+			}
+			html, err := executeViewTemplate(view, viewData)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			root, err := layoutDocument(html, 800, 480)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertBox(t, findBox(root, "header"), "header", 0, 0, 800, 50)
+			if content := findBox(root, "content"); content == nil || content.y < 50 || content.w > 800 {
+				t.Errorf("content box missing or outside the screen: %+v", content)
+			}
+
+			img, err := GoRenderer{}.Render(html, 800, 480)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if img.Bounds().Dx() != 800 || img.Bounds().Dy() != 480 {
+				t.Fatalf("image is %v, want 800x480", img.Bounds())
+			}
+			// Inverted header bar, white page below it, and some content drawn
+			if gray := color.GrayModel.Convert(img.At(400, 5)).(color.Gray); gray.Y > 64 {
+				t.Errorf("header background is %d, want dark", gray.Y)
+			}
+			dark := 0
+			for y := 60; y < 480; y++ {
+				for x := 0; x < 800; x++ {
+					if color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y < 128 {
+						dark++
+					}
+				}
+			}
+			if dark == 0 {
+				t.Error("nothing drawn below the header")
+			}
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/osteele/liquid"
+)
+
+// Template engines for views[].engine
+const (
+	engineGo     = "go"     // html/template with {{.Fields}} (default)
+	engineLiquid = "liquid" // Liquid with TRMNL-style variables, for pasting TRMNL plugin markup
+)
+
+// liquidEngine is shared by all views; it holds the TRMNL-compatible custom filters
+var liquidEngine = newLiquidEngine()
+
+// liquidStylesPattern matches the {{ styles }} injection point of full Liquid documents
+var liquidStylesPattern = regexp.MustCompile(`\{\{-?\s*styles\s*-?\}\}`)
+
+// viewEngine returns the engine configured for a view entry, defaulting to Liquid for
+// .liquid templates
+func viewEngine(entry ViewConfig) string {
+	if entry.Engine != "" {
+		return entry.Engine
+	}
+	if strings.EqualFold(filepath.Ext(entry.Template), ".liquid") {
+		return engineLiquid
+	}
+	return engineGo
+}
+
+func validateEngine(name string) error {
+	switch name {
+	case "", engineGo, engineLiquid:
+		return nil
+	}
+	return fmt.Errorf("unknown engine '%s' (expected go or liquid)", name)
+}
+
+// executeLiquidTemplate renders a Liquid view. Markup without an <html> element (as in a
+// TRMNL private plugin) is wrapped in a page with the base and framework styles.
+func executeLiquidTemplate(view View, viewData *ViewData) (string, error) {
+	source, err := os.ReadFile(view.Template)
+	if err != nil {
+		return "", fmt.Errorf("failed to read template '%s': %w", view.Template, err)
+	}
+
+	tmpl, srcErr := liquidEngine.ParseTemplateLocation(source, view.Template, 1)
+	if srcErr != nil {
+		return "", fmt.Errorf("failed to parse template '%s': %w", view.Template, srcErr)
+	}
+	out, srcErr := tmpl.RenderString(liquidBindings(view, viewData))
+	if srcErr != nil {
+		return "", fmt.Errorf("failed to execute template '%s': %w", view.Name, srcErr)
+	}
+
+	if !strings.Contains(strings.ToLower(out), "<html") {
+		out = liquidDocument(viewData, out)
+	}
+	return out, nil
+}
+
+// liquidDocument wraps plugin markup in a page sized for the display. Like TRMNL, a bare
+// layout is placed in a full-screen view.
+func liquidDocument(viewData *ViewData, markup string) string {
+	if strings.Contains(markup, `class="layout`) && !strings.Contains(markup, "view--") {
+		markup = `<div class="screen"><div class="view view--full">` + markup + `</div></div>`
+	}
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <meta name="viewport" content="width=%d, height=%d, initial-scale=1.0">
+  <title>%s</title>
+  <style>
+%s
+%s
+  </style>
+</head>
+<body>
+%s
+</body>
+</html>
+`, viewData.Width, viewData.Height, htmlEscaper.Replace(viewData.Title), viewData.Styles, viewData.TRMNLStyles, markup)
+}
+
+var htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&#34;")
+
+// liquidBindings exposes view data the way TRMNL does: merge variables at the top level
+// plus a trmnl object with user, device, system and plugin settings
+func liquidBindings(view View, viewData *ViewData) liquid.Bindings {
+	bindings := liquid.Bindings{}
+	for k, v := range viewData.raw {
+		bindings[k] = v
+	}
+
+	now := time.Now().Unix()
+	viewData.volatile = append(viewData.volatile, strconv.FormatInt(now, 10))
+
+	pluginSettings := map[string]interface{}{
+		"instance_name":        view.Name,
+		"custom_fields_values": jsonValue(view.Options),
+	}
+	bindings["trmnl"] = map[string]interface{}{
+		"user":            liquidUser(),
+		"device":          liquidDevice(viewData),
+		"system":          map[string]interface{}{"timestamp_utc": now},
+		"plugin_settings": pluginSettings,
+	}
+
+	// Local extensions
+	bindings["styles"] = string(viewData.Styles)
+	bindings["trmnl_styles"] = string(viewData.TRMNLStyles)
+	if _, ok := bindings["timestamp"]; !ok {
+		bindings["timestamp"] = viewData.Timestamp
+	}
+	if viewData.Alert != nil {
+		bindings["alert"] = jsonValue(viewData.Alert)
+	}
+	return bindings
+}
+
+// liquidUser builds trmnl.user from the trmnl.user section of config.json
+func liquidUser() map[string]interface{} {
+	user := config.TRMNL.User
+	first, last, _ := strings.Cut(user.Name, " ")
+
+	loc := time.Local
+	if user.TimeZone != "" {
+		if tz, err := time.LoadLocation(user.TimeZone); err == nil {
+			loc = tz
+		}
+	}
+	_, offset := time.Now().In(loc).Zone()
+
+	locale := user.Locale
+	if locale == "" {
+		locale = "en"
+	}
+	return map[string]interface{}{
+		"name":           user.Name,
+		"first_name":     first,
+		"last_name":      last,
+		"locale":         locale,
+		"time_zone":      loc.String(),
+		"time_zone_iana": loc.String(),
+		"utc_offset":     offset,
+	}
+}
+
+// liquidDevice builds trmnl.device from the most recently seen device. width and height are
+// the size the view is rendered at (its output profile or mashup region).
+func liquidDevice(viewData *ViewData) map[string]interface{} {
+	devices := viewData.Devices
+	device := map[string]interface{}{
+		"friendly_id": config.TRMNL.FriendlyID,
+		"width":       viewData.Width,
+		"height":      viewData.Height,
+	}
+
+	var latest *DeviceStatus
+	for i := range devices {
+		if devices[i].Telemetry != nil && (latest == nil || devices[i].LastSeen.After(latest.LastSeen)) {
+			latest = &devices[i]
+		}
+	}
+	if latest == nil {
+		return device
+	}
+
+	if latest.FriendlyID != "" {
+		device["friendly_id"] = latest.FriendlyID
+	}
+	device["name"] = latest.Name
+	device["mac_address"] = latest.MAC
+
+	sample := latest.Telemetry
+	if sample.FirmwareVersion != "" {
+		device["firmware_version"] = sample.FirmwareVersion
+	}
+	if sample.BatteryVoltage > 0 {
+		device["battery_voltage"] = sample.BatteryVoltage
+		device["percent_charged"] = batteryPercent(sample.BatteryVoltage)
+	}
+	if pct, err := strconv.ParseFloat(sample.Extra["Percent-Charged"], 64); err == nil {
+		device["percent_charged"] = pct
+	}
+	if sample.RSSI != 0 {
+		device["rssi"] = sample.RSSI
+		device["wifi_strength"] = wifiStrength(sample.RSSI)
+	}
+	return device
+}
+
+// batteryPercent estimates the charge of the LiPo cell from its voltage (3.0V empty, 4.2V full)
+func batteryPercent(voltage float64) float64 {
+	pct := (voltage - 3.0) / 1.2 * 100
+	return math.Round(math.Max(0, math.Min(100, pct)))
+}
+
+// wifiStrength maps RSSI to a 0-100 signal quality (-100 dBm = 0, -50 dBm = 100)
+func wifiStrength(rssi int) int {
+	return max(0, min(100, 2*(rssi+100)))
+}
+
+// jsonValue converts a Go value to plain maps and slices with JSON field names
+func jsonValue(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil
+	}
+	return value
+}
+
+// newLiquidEngine creates the Liquid engine with the filters TRMNL adds to standard Liquid
+func newLiquidEngine() *liquid.Engine {
+	engine := liquid.NewEngine()
+
+	engine.RegisterFilter("number_with_delimiter", func(value interface{}, delimiter func(string) string) string {
+		return formatNumber(value, delimiter(","), -1)
+	})
+	engine.RegisterFilter("number_to_currency", func(value interface{}, unit func(string) string) string {
+		return unit("$") + formatNumber(value, ",", 2)
+	})
+	engine.RegisterFilter("pluralize", func(count interface{}, singular string) string {
+		n, _ := toFloat(count)
+		word := singular
+		if n != 1 {
+			word = pluralizeWord(singular)
+		}
+		return strconv.FormatFloat(n, 'f', -1, 64) + " " + word
+	})
+	engine.RegisterFilter("where", func(list []interface{}, key string, value interface{}) []interface{} {
+		result := []interface{}{}
+		for _, item := range list {
+			if m, ok := item.(map[string]interface{}); ok && compareAlertValue(m[key], "==", value) {
+				result = append(result, item)
+			}
+		}
+		return result
+	})
+	engine.RegisterFilter("find_by", func(list []interface{}, key string, value interface{}) interface{} {
+		for _, item := range list {
+			if m, ok := item.(map[string]interface{}); ok && compareAlertValue(m[key], "==", value) {
+				return item
+			}
+		}
+		return nil
+	})
+	engine.RegisterFilter("group_by", func(list []interface{}, key string) map[string]interface{} {
+		groups := make(map[string]interface{})
+		for _, item := range list {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			group := fmt.Sprint(m[key])
+			existing, _ := groups[group].([]interface{})
+			groups[group] = append(existing, item)
+		}
+		return groups
+	})
+	engine.RegisterFilter("days_ago", func(days interface{}) time.Time {
+		n, _ := toFloat(days)
+		return time.Now().AddDate(0, 0, -int(n))
+	})
+	return engine
+}
+
+// formatNumber formats a number with a thousands delimiter and the given decimals (-1 = as is)
+func formatNumber(value interface{}, delimiter string, decimals int) string {
+	n, ok := toFloat(value)
+	if !ok {
+		return fmt.Sprint(value)
+	}
+
+	text := strconv.FormatFloat(n, 'f', decimals, 64)
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign, text = "-", text[1:]
+	}
+	whole, frac, hasFrac := strings.Cut(text, ".")
+
+	var b strings.Builder
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(delimiter)
+		}
+		b.WriteRune(r)
+	}
+	if hasFrac {
+		b.WriteString("." + frac)
+	}
+	return sign + b.String()
+}
+
+// pluralizeWord applies the common English plural rules
+func pluralizeWord(word string) string {
+	lower := strings.ToLower(word)
+	switch {
+	case strings.HasSuffix(lower, "y") && len(word) > 1 && !strings.ContainsAny(lower[len(lower)-2:len(lower)-1], "aeiou"):
+		return word[:len(word)-1] + "ies"
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return word + "es"
+	}
+	return word + "s"
+}
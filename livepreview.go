@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// previewPollInterval is how often an open preview checks its view's template and data
+// files. It is shorter than configWatchInterval to keep the edit loop tight.
+const previewPollInterval = 500 * time.Millisecond
+
+// previewKeepAlive keeps idle event streams from being closed by proxies
+const previewKeepAlive = 15 * time.Second
+
+// PreviewEvent is sent to /preview pages after every render of their view
+type PreviewEvent struct {
+	View     string   `json:"view"`
+	Version  int64    `json:"version"` // Changes with every render; used to bust image caches
+	Error    string   `json:"error,omitempty"`
+	Duration string   `json:"duration,omitempty"`
+	Warnings []string `json:"warnings"`
+	HasHTML  bool     `json:"hasHtml"` // False for mashups, which are composed from images
+	Width    int      `json:"width"`
+	Height   int      `json:"height"`
+}
+
+// PreviewHub fans render results out to open preview pages and keeps the HTML of the
+// latest render of each template view
+type PreviewHub struct {
+	mu          sync.Mutex
+	html        map[string]string
+	last        map[string]PreviewEvent
+	subscribers map[string]map[chan PreviewEvent]bool
+	watchers    map[string]chan struct{} // Stops the file watcher of a view
+}
+
+var previewHub = &PreviewHub{
+	html:        make(map[string]string),
+	last:        make(map[string]PreviewEvent),
+	subscribers: make(map[string]map[chan PreviewEvent]bool),
+	watchers:    make(map[string]chan struct{}),
+}
+
+// Publish records a render of a view and notifies its preview pages
+func (h *PreviewHub) Publish(view View, start time.Time, result viewRenderResult, err error) {
+	event := PreviewEvent{
+		View:     view.Name,
+		Version:  time.Now().UnixMilli(),
+		Duration: time.Since(start).Round(time.Millisecond).String(),
+		Warnings: result.Warnings,
+		HasHTML:  view.Mashup == nil,
+		Width:    config.Render.Width,
+		Height:   config.Render.Height,
+	}
+	if event.Warnings == nil {
+		event.Warnings = []string{}
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err == nil && result.HTML != "" {
+		h.html[view.Name] = result.HTML
+	}
+	h.last[view.Name] = event
+	for ch := range h.subscribers[view.Name] {
+		// A page that hasn't read the previous event only gets the newest one
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- event
+	}
+}
+
+// HTML returns the HTML of the latest render of a template view
+func (h *PreviewHub) HTML(name string) (string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	html, ok := h.html[name]
+	return html, ok
+}
+
+// Subscribe registers a preview page for a view. The first subscriber of a view starts
+// watching its files; the returned function unsubscribes.
+func (h *PreviewHub) Subscribe(name string) (chan PreviewEvent, func()) {
+	ch := make(chan PreviewEvent, 1)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers[name] == nil {
+		h.subscribers[name] = make(map[chan PreviewEvent]bool)
+	}
+	h.subscribers[name][ch] = true
+	if last, ok := h.last[name]; ok {
+		ch <- last
+	}
+	if _, ok := h.watchers[name]; !ok {
+		stop := make(chan struct{})
+		h.watchers[name] = stop
+		go watchPreviewFiles(name, stop)
+	}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subscribers[name], ch)
+		if len(h.subscribers[name]) == 0 {
+			delete(h.subscribers, name)
+			if stop, ok := h.watchers[name]; ok {
+				close(stop)
+				delete(h.watchers, name)
+			}
+		}
+	}
+}
+
+// previewFiles lists the template and data files a view is rendered from
+func previewFiles(view View) []string {
+	var files []string
+	if view.Template != "" {
+		files = append(files, view.Template)
+	}
+	for _, source := range view.dataSources() {
+		if source.Type == sourceFile && source.Path != "" {
+			files = append(files, source.Path)
+		}
+	}
+	if view.Mashup != nil {
+		for _, child := range view.Mashup.Children {
+			files = append(files, previewFiles(child)...)
+		}
+	}
+	return files
+}
+
+// previewFingerprint identifies the current version of a view's files
+func previewFingerprint(files []string) string {
+	parts := make([]string, 0, len(files))
+	for _, path := range files {
+		if info, err := os.Stat(path); err == nil {
+			parts = append(parts, path+"="+fileFingerprint(info))
+		} else {
+			parts = append(parts, path+"=missing")
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// watchPreviewFiles re-renders a view whenever its template or data files change, until stop
+// is closed
+func watchPreviewFiles(name string, stop chan struct{}) {
+	ticker := time.NewTicker(previewPollInterval)
+	defer ticker.Stop()
+
+	last := ""
+	for {
+		configMu.RLock()
+		view, ok := findView(name)
+		fingerprint := previewFingerprint(previewFiles(view))
+		if ok && fingerprint != last {
+			if last != "" {
+				log.Printf("Preview: files of view %s changed, re-rendering...", name)
+			}
+			// Also renders when the first page opens, so it starts from the current files
+			if _, err := renderView(view); err != nil {
+				log.Printf("Warning: Preview render of view %s failed: %v", name, err)
+			}
+			last = fingerprint
+		}
+		configMu.RUnlock()
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handlePreviewPage serves /preview/<view> (the live preview page) and
+// /preview/<view>/html (the HTML of the latest render)
+func handlePreviewPage(w http.ResponseWriter, r *http.Request) {
+	name, raw := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/preview/"), "/html")
+	view, ok := findView(name)
+	if !ok {
+		http.Error(w, "Unknown view: "+name, http.StatusNotFound)
+		return
+	}
+
+	if raw {
+		html, ok := previewHub.HTML(view.Name)
+		if !ok {
+			rendered, err := renderViewHTML(view)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			html = rendered
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+		fmt.Fprint(w, html)
+		return
+	}
+
+	page, err := adminFiles.ReadFile("admin/preview.html")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
+
+// handlePreviewEvents streams a PreviewEvent for every render of ?view=<name> as
+// Server-Sent Events. It runs without the config lock, which it would otherwise hold for
+// as long as the page is open.
+func handlePreviewEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	name := r.URL.Query().Get("view")
+	configMu.RLock()
+	_, ok = findView(name)
+	configMu.RUnlock()
+	if !ok {
+		http.Error(w, "Unknown view: "+name, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	events, unsubscribe := previewHub.Subscribe(name)
+	defer unsubscribe()
+
+	keepAlive := time.NewTicker(previewKeepAlive)
+	defer keepAlive.Stop()
+
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case event := <-events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: render\ndata: %s\n\n", data)
+		}
+		flusher.Flush()
+	}
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"net"
@@ -14,8 +13,9 @@ import (
 
 type Config struct {
 	Server struct {
-		Port string `json:"port"`
-		Host string `json:"host"`
+		Port         string `json:"port"`
+		Host         string `json:"host"`
+		WebhookToken string `json:"webhookToken"` // Bearer token for POST/PATCH /api/views/<name>/data
 	} `json:"server"`
 	Render struct {
 		Width               int    `json:"width"`
@@ -23,21 +23,44 @@ type Config struct {
 		RefreshIntervalMins int    `json:"refreshIntervalMinutes"`
 		OutputPath          string `json:"outputPath"`
 		TempPath            string `json:"tempPath"`
+		MaxConcurrentPages  int    `json:"maxConcurrentPages"`
+		Renderer            string `json:"renderer"`        // auto, playwright or go
+		Cache               *bool  `json:"cache,omitempty"` // Reuse the image of unchanged HTML (default: true)
+		DitherSettings             // dither, threshold, gamma, contrast
+
+		Profiles map[string]OutputProfile `json:"profiles,omitempty"` // Output profiles devices can use instead of width/height
 	} `json:"render"`
 	DataSources struct {
-		JSONFiles     []string `json:"jsonFiles"`
-		APIEndpoints  []string `json:"apiEndpoints"`
-		Scripts       []string `json:"scripts"`
+		JSONFiles     []string       `json:"jsonFiles"`
+		APIEndpoints  []string       `json:"apiEndpoints"`
+		Scripts       []ScriptSource `json:"scripts"` // Command string or {name, command, args, dir, env, timeoutSeconds}
 	} `json:"dataSources"`
 	TRMNL struct {
 		APIKey          string `json:"apiKey"`
 		FriendlyID      string `json:"friendlyId"`
 		RefreshRateSecs int    `json:"refreshRateSeconds"`
+		User            struct {
+			Name     string `json:"name"`
+			Locale   string `json:"locale"`   // e.g. "en", "de"
+			TimeZone string `json:"timeZone"` // IANA name, e.g. "Europe/Berlin" (default: server time zone)
+		} `json:"user"` // Exposed to Liquid templates as trmnl.user
+		RefreshRules RefreshRules `json:"refreshRules"` // Adaptive refresh_rate (quiet hours, alerts, battery)
 	} `json:"trmnl"`
 	Paths struct {
-		Template  string `json:"template"`
-		OutputDir string `json:"outputDir"`
+		Template    string `json:"template"`
+		OutputDir   string `json:"outputDir"`
+		DevicesFile string `json:"devicesFile"`
 	} `json:"paths"`
+	Firmware struct {
+		Dir            string `json:"dir"`                      // Directory with firmware .bin files (default: ./firmware)
+		RolloutPercent *int   `json:"rolloutPercent,omitempty"` // Share of opted-in devices offered a new version (default: 100)
+	} `json:"firmware"`
+	DeviceLogs struct {
+		Dir       string `json:"dir"`       // Directory for logs posted to /api/log (default: ./logs)
+		MaxFileKB int    `json:"maxFileKB"` // Size of a log file before it is rotated (default: 512)
+		MaxFiles  int    `json:"maxFiles"`  // Files kept per device, including the current one (default: 4)
+	} `json:"deviceLogs"`
+	Views []ViewConfig `json:"views"`
 }
 
 type ViewModel struct {
@@ -63,6 +86,8 @@ type RenderStats struct {
 	ConversionDuration  time.Duration
 	OutputSize          int64
 	LastRenderTime      time.Time
+	CacheHits           int // Views whose previous image was reused in the last run
+	CacheMisses         int // Views that were rendered
 }
 
 // Shared variables for graceful shutdown
@@ -71,22 +96,24 @@ var serverQuitChan chan bool
 
 func main() {
 	// Load config
-	configData, err := os.ReadFile("config.json")
+	loadedConfig, loadedViews, err := loadConfig(configPath)
 	if err != nil {
-		log.Fatalf("Failed to read config.json: %v", err)
-	}
-	if err := json.Unmarshal(configData, &config); err != nil {
-		log.Fatalf("Failed to parse config.json: %v", err)
+		log.Fatalf("Failed to load config: %v", err)
 	}
+	config = loadedConfig
+	views = loadedViews
+	lastRotationTime = time.Now()
 
 	// Ensure output directory exists
 	if err := os.MkdirAll(config.Paths.OutputDir, 0755); err != nil {
 		log.Fatalf("Failed to create output directory: %v", err)
 	}
 
-	// Initialize views
-	initViews()
-	lastRotationTime = time.Now()
+	// Load registered devices
+	deviceRegistry, err = loadDeviceRegistry(config.Paths.DevicesFile)
+	if err != nil {
+		log.Fatalf("Failed to load devices: %v", err)
+	}
 
 	// Check if we should render a specific view for testing
 	// Note: This requires test-render.go to be included in the build
@@ -110,17 +137,34 @@ func main() {
 		return
 	}
 
-	// Perform initial render of all views
-	log.Println("Performing initial render of all views...")
-	if err := renderAllViews(); err != nil {
-		log.Printf("Initial render failed: %v", err)
+	// Live preview of one view: serve /preview/<view> without driving the device
+	previewName := ""
+	if len(os.Args) > 1 && os.Args[1] == "preview" {
+		if len(os.Args) < 3 {
+			log.Fatal("Usage: trmnl-renderer preview <view>")
+		}
+		previewName = os.Args[2]
+		if _, ok := findView(previewName); !ok {
+			log.Fatalf("Unknown view: %s", previewName)
+		}
 	}
 
-	// Start scheduled rendering
-	go startScheduler()
+	if previewName == "" {
+		// Perform initial render of all views
+		log.Println("Performing initial render of all views...")
+		if err := renderAllViews(); err != nil {
+			log.Printf("Initial render failed: %v", err)
+		}
+
+		// Start scheduled rendering
+		go startScheduler()
+
+		// Start view rotation
+		go startViewRotation()
+	}
 
-	// Start view rotation
-	go startViewRotation()
+	// Watch config.json, templates and data for changes
+	go startConfigWatcher()
 
 	// Setup HTTP server
 	setupServer()
@@ -136,7 +180,7 @@ func main() {
 	baseURL := fmt.Sprintf("http://%s:%s", localIP, config.Server.Port)
 	
 	// Check for --no-tray flag to run in console mode
-	useTray := true
+	useTray := previewName == ""
 	for _, arg := range os.Args[1:] {
 		if arg == "--no-tray" {
 			useTray = false
@@ -145,7 +189,9 @@ func main() {
 	}
 
 	// Display startup help text
-	displayStartupHelp(baseURL, localIP, config.Server.Port)
+	if previewName == "" {
+		displayStartupHelp(baseURL, localIP, config.Server.Port)
+	}
 	
 	log.Printf("\nTRMNL Renderer service listening on http://%s", addr)
 	log.Printf("Endpoints:")
@@ -153,11 +199,17 @@ func main() {
 	log.Printf("  - TRMNL Display: http://%s/api/display", baseURL)
 	log.Printf("  - Image: http://%s/screen.bmp", baseURL)
 	log.Printf("  - Manual Render: POST http://%s/api/render", baseURL)
+	log.Printf("  - Admin UI: %s/admin/", baseURL)
+	if previewName != "" {
+		previewURL := fmt.Sprintf("http://localhost:%s/preview/%s", config.Server.Port, previewName)
+		log.Printf("Live preview of view '%s': %s (scheduler and rotation are off)", previewName, previewURL)
+		openBrowser(previewURL)
+	}
 
 	// Create HTTP server with graceful shutdown support
 	httpServer = &http.Server{
 		Addr:    addr,
-		Handler: nil, // Uses http.DefaultServeMux from setupServer()
+		Handler: withConfigReadLock(http.DefaultServeMux), // Routes from setupServer()
 	}
 
 	// Start system tray if on Windows and not disabled
@@ -199,6 +251,9 @@ func main() {
 		} else {
 			log.Println("Server stopped successfully")
 		}
+		
+		// Stop the headless browser worker
+		getBrowserWorker().Close()
 	} else {
 		// Console mode - wait for Ctrl+C or keep running
 		// Note: On Windows, console can be minimized when tray is active
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/image/draw"
+)
+
+// viewTypeMashup is the views[].type of a view composed from other views
+const viewTypeMashup = "mashup"
+
+// Mashup places several template views in regions of one screen
+type Mashup struct {
+	Layout   string
+	Scale    float64 // Children render at region size / Scale, then are scaled into the region
+	Children []View  // One per region of the layout, in order
+}
+
+// mashupRegion is a part of the screen, in fractions of its width and height
+type mashupRegion struct {
+	X, Y, W, H float64
+}
+
+// mashupLayouts names the supported layouts after TRMNL's mashups: L/R/T/B are left, right,
+// top and bottom, so "1Lx2R" is one big view on the left and two small ones on the right
+var mashupLayouts = map[string][]mashupRegion{
+	"1Lx1R": {{0, 0, 0.5, 1}, {0.5, 0, 0.5, 1}},
+	"1Tx1B": {{0, 0, 1, 0.5}, {0, 0.5, 1, 0.5}},
+	"1Lx2R": {{0, 0, 0.5, 1}, {0.5, 0, 0.5, 0.5}, {0.5, 0.5, 0.5, 0.5}},
+	"2Lx1R": {{0, 0, 0.5, 0.5}, {0, 0.5, 0.5, 0.5}, {0.5, 0, 0.5, 1}},
+	"1Tx2B": {{0, 0, 1, 0.5}, {0, 0.5, 0.5, 0.5}, {0.5, 0.5, 0.5, 0.5}},
+	"2Tx1B": {{0, 0, 0.5, 0.5}, {0.5, 0, 0.5, 0.5}, {0, 0.5, 1, 0.5}},
+	"2x2":   {{0, 0, 0.5, 0.5}, {0.5, 0, 0.5, 0.5}, {0, 0.5, 0.5, 0.5}, {0.5, 0.5, 0.5, 0.5}},
+}
+
+// mashupDivider is the width of the black line drawn between regions
+const mashupDivider = 2
+
+// validateMashup checks the mashup fields of a view entry; the child names are resolved later
+func validateMashup(entry ViewConfig) error {
+	errors := []string{}
+
+	regions, ok := mashupLayouts[entry.Layout]
+	if !ok {
+		names := make([]string, 0, len(mashupLayouts))
+		for name := range mashupLayouts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		errors = append(errors, fmt.Sprintf("unknown layout '%s' (expected %s)", entry.Layout, strings.Join(names, ", ")))
+	} else if len(entry.Views) != len(regions) {
+		errors = append(errors, fmt.Sprintf("layout %s needs %d views, got %d", entry.Layout, len(regions), len(entry.Views)))
+	}
+	for _, name := range entry.Views {
+		if name == entry.Name {
+			errors = append(errors, "a mashup cannot contain itself")
+		}
+	}
+	if entry.Scale < 0 || entry.Scale > 1 {
+		errors = append(errors, "scale must be between 0 and 1")
+	}
+	if entry.Template != "" || entry.DataPath != "" || len(entry.Sources) > 0 {
+		errors = append(errors, "template, dataPath and sources belong to the views in the mashup")
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, "; "))
+	}
+	return nil
+}
+
+// contains reports whether the mashup shows the named view
+func (m *Mashup) contains(name string) bool {
+	for _, child := range m.Children {
+		if child.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// rect returns the region's pixel rectangle on a width x height screen
+func (r mashupRegion) rect(width, height int) image.Rectangle {
+	x0 := int(r.X * float64(width))
+	y0 := int(r.Y * float64(height))
+	x1 := int((r.X + r.W) * float64(width))
+	y1 := int((r.Y + r.H) * float64(height))
+	return image.Rect(x0, y0, x1, y1)
+}
+
+// mashupPart is one child of a mashup, rendered to HTML at its region size (divided by the
+// mashup's scale)
+type mashupPart struct {
+	child         View
+	region        image.Rectangle
+	width, height int
+	html          string
+	volatile      []string
+	err           error
+}
+
+// composeMashup renders each child view into its region and returns the composed screen
+// (before dithering). A failing child leaves its region blank; the mashup only fails when
+// every child failed.
+func composeMashup(view View, profile OutputProfile) (image.Image, viewRenderResult, error) {
+	var result viewRenderResult
+	parts := prepareMashup(view, profile, &result)
+	img, err := drawMashup(view, parts, profile, &result)
+	return img, result, err
+}
+
+// prepareMashup loads the data and executes the template of every child view
+func prepareMashup(view View, profile OutputProfile, result *viewRenderResult) []mashupPart {
+	width, height := profile.layoutSize()
+	regions := mashupLayouts[view.Mashup.Layout]
+	scale := view.Mashup.Scale
+	if scale == 0 {
+		scale = 1
+	}
+
+	parts := make([]mashupPart, len(view.Mashup.Children))
+	for i, child := range view.Mashup.Children {
+		part := &parts[i]
+		part.child = child
+		part.region = regions[i].rect(width, height)
+		part.width = int(float64(part.region.Dx()) / scale)
+		part.height = int(float64(part.region.Dy()) / scale)
+		part.html, part.volatile, part.err = mashupChildHTML(child, part.width, part.height, result)
+	}
+	return parts
+}
+
+// drawMashup renders the children's HTML into their regions and draws the dividers
+func drawMashup(view View, parts []mashupPart, profile OutputProfile, result *viewRenderResult) (image.Image, error) {
+	width, height := profile.layoutSize()
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+
+	failed := 0
+	var lastErr error
+	for _, part := range parts {
+		err := part.err
+		if err == nil {
+			imgStart := time.Now()
+			var img image.Image
+			img, err = getRenderer().Render(part.html, part.width, part.height)
+			result.ImageDuration += time.Since(imgStart)
+			if err == nil {
+				draw.CatmullRom.Scale(canvas, part.region, img, img.Bounds(), draw.Src, nil)
+				continue
+			}
+			err = fmt.Errorf("failed to render image: %w", err)
+		}
+		log.Printf("Warning: Mashup %s: view %s: %v", view.Name, part.child.Name, err)
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%s: %v", part.child.Name, err))
+		lastErr = err
+		failed++
+	}
+	if failed == len(parts) {
+		return nil, fmt.Errorf("every view of mashup %s failed: %w", view.Name, lastErr)
+	}
+
+	// Dividers along the inner edges of the regions
+	black := image.NewUniform(color.Black)
+	for _, part := range parts {
+		r := part.region
+		if r.Max.X < width {
+			draw.Draw(canvas, image.Rect(r.Max.X-mashupDivider/2, r.Min.Y, r.Max.X+mashupDivider/2, r.Max.Y), black, image.Point{}, draw.Src)
+		}
+		if r.Max.Y < height {
+			draw.Draw(canvas, image.Rect(r.Min.X, r.Max.Y-mashupDivider/2, r.Max.X, r.Max.Y+mashupDivider/2), black, image.Point{}, draw.Src)
+		}
+	}
+	return canvas, nil
+}
+
+// mashupCacheKey identifies a composed mashup by the HTML and region of each child
+func mashupCacheKey(view View, parts []mashupPart, profile OutputProfile) string {
+	var b strings.Builder
+	volatile := []string{}
+	fmt.Fprintf(&b, "mashup %s\n", view.Mashup.Layout)
+	for _, part := range parts {
+		fmt.Fprintf(&b, "%s %v %dx%d\n", part.child.Name, part.region, part.width, part.height)
+		if part.err != nil {
+			fmt.Fprintf(&b, "error: %v\n", part.err)
+		} else {
+			b.WriteString(part.html)
+			volatile = append(volatile, part.volatile...)
+		}
+	}
+	return renderCacheKey(b.String(), profile, view.Dither, volatile)
+}
+
+// mashupChildHTML loads a child view's data and executes its template at the given size. It
+// also returns the volatile values of the HTML (see renderCacheKey).
+func mashupChildHTML(child View, width, height int, result *viewRenderResult) (string, []string, error) {
+	for _, warning := range validateViewBeforeRender(child) {
+		result.Warnings = append(result.Warnings, child.Name+": "+warning)
+	}
+
+	dataStart := time.Now()
+	viewData, err := loadViewData(child)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load data: %w", err)
+	}
+	result.DataDuration += time.Since(dataStart)
+	if checkViewAlerts(child, viewData.raw) {
+		showAlertChange(child)
+	}
+
+	htmlStart := time.Now()
+	viewData.setSize(width, height)
+	html, err := executeViewTemplate(child, viewData)
+	if err != nil {
+		return "", nil, err
+	}
+	result.HTMLDuration += time.Since(htmlStart)
+	return html, viewData.volatile, nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ViewStatus is the outcome of the most recent render of a view (exposed via /api/views)
+type ViewStatus struct {
+	LastRender time.Time `json:"lastRender,omitzero"` // Last successful render
+	LastError  string    `json:"lastError,omitempty"` // Set when the most recent render failed
+	ErrorTime  time.Time `json:"errorTime,omitzero"`
+	Duration   string    `json:"duration,omitempty"`
+	Size       int64     `json:"size,omitempty"` // PNG size in bytes
+	Cached     bool      `json:"cached"`         // The last render reused the previous image
+	Warnings   []string  `json:"warnings"`
+}
+
+// ViewStatusStore keeps the latest render outcome per view name
+type ViewStatusStore struct {
+	mu       sync.Mutex
+	statuses map[string]ViewStatus
+}
+
+var viewStatusStore = &ViewStatusStore{statuses: make(map[string]ViewStatus)}
+
+// Record stores the outcome of a render that started at start
+func (s *ViewStatusStore) Record(name string, start time.Time, result viewRenderResult, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := s.statuses[name]
+	status.Warnings = result.Warnings
+	if status.Warnings == nil {
+		status.Warnings = []string{}
+	}
+	if err != nil {
+		status.LastError = err.Error()
+		status.ErrorTime = time.Now()
+	} else {
+		status.LastError = ""
+		status.ErrorTime = time.Time{}
+		status.LastRender = time.Now()
+		status.Duration = time.Since(start).Round(time.Millisecond).String()
+		status.Size = result.Size
+		status.Cached = result.Cached
+	}
+	s.statuses[name] = status
+}
+
+// Get returns the recorded status of a view
+func (s *ViewStatusStore) Get(name string) ViewStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status, ok := s.statuses[name]
+	if !ok {
+		status.Warnings = []string{}
+	}
+	return status
+}
+
+// previewViews returns the rotating views followed by views only shown inside mashups
+func previewViews() []View {
+	result := append([]View{}, views...)
+	seen := make(map[string]bool)
+	for _, view := range views {
+		seen[view.Name] = true
+	}
+	for _, view := range views {
+		if view.Mashup == nil {
+			continue
+		}
+		for _, child := range view.Mashup.Children {
+			if !seen[child.Name] {
+				seen[child.Name] = true
+				result = append(result, child)
+			}
+		}
+	}
+	return result
+}
+
+// handleViewList serves GET /api/views: every view with its latest render outcome
+func handleViewList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	type viewInfo struct {
+		Name     string   `json:"name"`
+		Type     string   `json:"type"` // template or mashup
+		Engine   string   `json:"engine,omitempty"`
+		Template string   `json:"template,omitempty"`
+		Layout   string   `json:"layout,omitempty"`
+		Views    []string `json:"views,omitempty"`
+		Rotating bool     `json:"rotating"` // False for views only shown inside mashups
+		Current  bool     `json:"current"`
+		PNG      string   `json:"png"`
+		BMP      string   `json:"bmp"`
+		ViewStatus
+	}
+
+	rotating := make(map[string]bool)
+	for _, view := range views {
+		rotating[view.Name] = true
+	}
+	current := currentRotation().view.Name
+
+	result := []viewInfo{}
+	for _, view := range previewViews() {
+		info := viewInfo{
+			Name:       view.Name,
+			Type:       "template",
+			Engine:     view.Engine,
+			Template:   view.Template,
+			Rotating:   rotating[view.Name],
+			Current:    view.Name == current,
+			PNG:        "/views/" + view.Name + ".png",
+			BMP:        "/views/" + view.Name + ".bmp",
+			ViewStatus: viewStatusStore.Get(view.Name),
+		}
+		if view.Mashup != nil {
+			info.Type = viewTypeMashup
+			info.Layout = view.Mashup.Layout
+			for _, child := range view.Mashup.Children {
+				info.Views = append(info.Views, child.Name)
+			}
+		}
+		result = append(result, info)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"views": result})
+}
+
+// serveViewImage serves GET /views/<name>.png and .bmp, the latest image of any view.
+// Add ?render=1 to re-render the view first and ?profile=<name> for an output profile.
+func serveViewImage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	name := strings.TrimPrefix(r.URL.Path, "/views/")
+	ext := filepath.Ext(name)
+	if ext != ".bmp" && ext != ".png" {
+		http.NotFound(w, r)
+		return
+	}
+
+	view, ok := findView(strings.TrimSuffix(name, ext))
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Unknown view"})
+		return
+	}
+
+	profile, ok := findProfile(r.URL.Query().Get("profile"))
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Unknown profile"})
+		return
+	}
+
+	if r.URL.Query().Get("render") != "" {
+		if _, err := renderView(view); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+	}
+
+	// Only profiles bound to a device are rendered
+	path := filepath.Join(config.Paths.OutputDir, profile.imageName(view.Name)+ext)
+	if _, err := os.Stat(path); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Image not yet generated"})
+		return
+	}
+
+	// Served with its ETag and Cache-Control: no-cache like the device images
+	serveImageFile(w, r, path)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// OutputProfile describes a display: the panel's native resolution, its gray levels and how
+// it is mounted. Views are laid out at the rotated size and turned to fit the panel.
+type OutputProfile struct {
+	Name     string `json:"-"`
+	Width    int    `json:"width"`              // Panel width in pixels, as the device reports it
+	Height   int    `json:"height"`             // Panel height in pixels
+	BitDepth int    `json:"bitDepth,omitempty"` // 1 (black and white), 2 or 4 (4 or 16 grays); default 1
+	Rotate   int    `json:"rotate,omitempty"`   // Clockwise rotation of the image: 90 or 270 for panels mounted in portrait, 180 upside down
+}
+
+// defaultProfileName is the profile built from render.width and render.height, used by
+// /screen.bmp and every device without a profile
+const defaultProfileName = "default"
+
+// defaultProfile returns the 1-bit profile of render.width x render.height
+func defaultProfile() OutputProfile {
+	return OutputProfile{
+		Name:     defaultProfileName,
+		Width:    config.Render.Width,
+		Height:   config.Render.Height,
+		BitDepth: 1,
+	}
+}
+
+// validateProfiles checks the render.profiles section
+func validateProfiles(profiles map[string]OutputProfile) error {
+	errors := []string{}
+
+	for name, profile := range profiles {
+		if name == defaultProfileName {
+			errors = append(errors, `"default" is reserved for render.width and render.height`)
+			continue
+		}
+		if !viewNamePattern.MatchString(name) {
+			errors = append(errors, fmt.Sprintf("%q: name may only contain letters, digits, - and _", name))
+		}
+		if profile.Width <= 0 || profile.Height <= 0 {
+			errors = append(errors, fmt.Sprintf("%s: width and height must be positive", name))
+		}
+		switch profile.BitDepth {
+		case 0, 1, 2, 4:
+		default:
+			errors = append(errors, fmt.Sprintf("%s: bitDepth must be 1, 2 or 4", name))
+		}
+		switch profile.Rotate {
+		case 0, 90, 180, 270:
+		default:
+			errors = append(errors, fmt.Sprintf("%s: rotate must be 0, 90, 180 or 270", name))
+		}
+	}
+
+	if len(errors) > 0 {
+		sort.Strings(errors)
+		return fmt.Errorf("%s", strings.Join(errors, "; "))
+	}
+	return nil
+}
+
+// findProfile resolves a profile name ("" and "default" are the default profile)
+func findProfile(name string) (OutputProfile, bool) {
+	if name == "" || name == defaultProfileName {
+		return defaultProfile(), true
+	}
+	profile, ok := config.Render.Profiles[name]
+	if !ok {
+		return OutputProfile{}, false
+	}
+	profile.Name = name
+	if profile.BitDepth == 0 {
+		profile.BitDepth = 1
+	}
+	return profile, true
+}
+
+// deviceProfile returns the output profile bound to a device. Unknown names fall back to
+// the default profile.
+func deviceProfile(device *Device) OutputProfile {
+	if device == nil {
+		return defaultProfile()
+	}
+	profile, ok := findProfile(deviceRegistry.Snapshot(device).Profile)
+	if !ok {
+		return defaultProfile()
+	}
+	return profile
+}
+
+// activeProfiles returns the non-default profiles bound to at least one registered device;
+// views are rendered for these in addition to the default profile
+func activeProfiles() []OutputProfile {
+	if deviceRegistry == nil {
+		return nil
+	}
+
+	used := make(map[string]bool)
+	for _, device := range deviceRegistry.List() {
+		if _, ok := findProfile(device.Profile); ok && device.Profile != "" && device.Profile != defaultProfileName {
+			used[device.Profile] = true
+		}
+	}
+
+	profiles := []OutputProfile{}
+	for name := range used {
+		profile, _ := findProfile(name)
+		profiles = append(profiles, profile)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles
+}
+
+// warnUnknownProfiles logs devices bound to a profile missing from render.profiles
+func warnUnknownProfiles() {
+	if deviceRegistry == nil {
+		return
+	}
+	for _, device := range deviceRegistry.List() {
+		if _, ok := findProfile(device.Profile); !ok {
+			log.Printf("Warning: Device %s uses unknown profile %q, showing the default profile", device.FriendlyID, device.Profile)
+		}
+	}
+}
+
+// renderProfiles returns the default profile followed by the active profiles
+func renderProfiles() []OutputProfile {
+	return append([]OutputProfile{defaultProfile()}, activeProfiles()...)
+}
+
+// layoutSize is the size views are rendered at: the panel size, swapped for portrait mounts
+func (p OutputProfile) layoutSize() (int, int) {
+	if p.Rotate == 90 || p.Rotate == 270 {
+		return p.Height, p.Width
+	}
+	return p.Width, p.Height
+}
+
+// imageName returns the output file name (without extension) of an image for this profile:
+// the name itself for the default profile, <name>@<profile> otherwise
+func (p OutputProfile) imageName(name string) string {
+	if p.Name == "" || p.Name == defaultProfileName {
+		return name
+	}
+	return name + "@" + p.Name
+}
+
+// imageExt is the format devices with this profile fetch: 1-bit BMP, or PNG for grayscale
+func (p OutputProfile) imageExt() string {
+	if p.BitDepth > 1 {
+		return ".png"
+	}
+	return ".bmp"
+}
+
+// rotateGray turns a row-major width x height buffer clockwise by degrees (0, 90, 180, 270)
+// and returns it with its new size
+func rotateGray(gray []float64, width, height, degrees int) ([]float64, int, int) {
+	if degrees == 0 {
+		return gray, width, height
+	}
+
+	out := make([]float64, len(gray))
+	switch degrees {
+	case 90:
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				out[x*height+(height-1-y)] = gray[y*width+x]
+			}
+		}
+		return out, height, width
+	case 180:
+		for i, v := range gray {
+			out[len(gray)-1-i] = v
+		}
+		return out, width, height
+	case 270:
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				out[(width-1-x)*height+y] = gray[y*width+x]
+			}
+		}
+		return out, height, width
+	}
+	return gray, width, height
+}
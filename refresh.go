@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RefreshRules adjust the refresh_rate returned by /api/display for each poll
+type RefreshRules struct {
+	QuietHours           []QuietHours    `json:"quietHours,omitempty"`
+	AlertRefreshRateSecs int             `json:"alertRefreshRateSeconds,omitempty"` // Poll at most this often apart while an alert is active
+	AlignToViewChanges   bool            `json:"alignToViewChanges,omitempty"`      // Wake up when the device's view changes
+	LowBattery           *LowBatteryRule `json:"lowBattery,omitempty"`
+	MinRefreshRateSecs   int             `json:"minRefreshRateSeconds,omitempty"` // Lower bound for every rule (default: 30)
+}
+
+// QuietHours is a weekday/time window with a long refresh rate. Devices still wake up
+// when the window ends.
+type QuietHours struct {
+	Days            []string `json:"days,omitempty"` // "mon".."sun" or ranges like "mon-fri" (empty = every day)
+	Start           string   `json:"start"`          // "HH:MM"
+	End             string   `json:"end"`            // "HH:MM" (exclusive, may wrap past midnight)
+	RefreshRateSecs int      `json:"refreshRateSeconds"`
+}
+
+// LowBatteryRule slows polling down while a device reports a low battery voltage
+type LowBatteryRule struct {
+	BelowVoltage    float64 `json:"belowVoltage"`
+	RefreshRateSecs int     `json:"refreshRateSeconds"`
+}
+
+// defaultMinRefreshRate keeps aligned refresh rates from waking the device in a tight loop
+const defaultMinRefreshRate = 30
+
+// rotationCheckDelay is how long after a view change is due the global rotation picks it
+// up (startViewRotation checks once a minute)
+const rotationCheckDelay = time.Minute
+
+// RefreshDecision is the refresh rate last sent to a device and why (exposed via /api/devices)
+type RefreshDecision struct {
+	Rate   int       `json:"rate"`
+	Reason string    `json:"reason"`
+	Time   time.Time `json:"time"`
+}
+
+var (
+	refreshDecisionsMu sync.Mutex
+	refreshDecisions   = make(map[string]RefreshDecision) // By telemetry key
+)
+
+// validateRefreshRules checks the trmnl.refreshRules section
+func validateRefreshRules(rules RefreshRules) error {
+	errors := []string{}
+
+	for i, quiet := range rules.QuietHours {
+		if _, err := compileQuietHours(quiet); err != nil {
+			errors = append(errors, fmt.Sprintf("quietHours[%d]: %v", i, err))
+		}
+		if quiet.RefreshRateSecs <= 0 {
+			errors = append(errors, fmt.Sprintf("quietHours[%d]: refreshRateSeconds must be positive", i))
+		}
+	}
+	if rules.AlertRefreshRateSecs < 0 {
+		errors = append(errors, "alertRefreshRateSeconds must not be negative")
+	}
+	if rules.LowBattery != nil {
+		if rules.LowBattery.BelowVoltage <= 0 {
+			errors = append(errors, "lowBattery.belowVoltage must be positive")
+		}
+		if rules.LowBattery.RefreshRateSecs <= 0 {
+			errors = append(errors, "lowBattery.refreshRateSeconds must be positive")
+		}
+	}
+	if rules.MinRefreshRateSecs < 0 {
+		errors = append(errors, "minRefreshRateSeconds must not be negative")
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, "; "))
+	}
+	return nil
+}
+
+func compileQuietHours(quiet QuietHours) (scheduleRule, error) {
+	if quiet.Start == "" || quiet.End == "" {
+		return scheduleRule{}, fmt.Errorf("start and end are required")
+	}
+	return compileScheduleRule(ScheduleRule{Days: quiet.Days, Start: quiet.Start, End: quiet.End})
+}
+
+// refreshRateFor computes the refresh rate for a poll. base is the configured rate of the
+// device; key identifies it for telemetry. The rules apply in order: quiet hours, low
+// battery, alerts, then alignment to the next view change.
+func refreshRateFor(device *Device, key string, base int, now time.Time) (int, string) {
+	rules := config.TRMNL.RefreshRules
+	minRate := rules.MinRefreshRateSecs
+	if minRate == 0 {
+		minRate = defaultMinRefreshRate
+	}
+
+	rate := base
+	reasons := []string{"configured"}
+	quiet := false
+
+	for _, window := range rules.QuietHours {
+		rule, err := compileQuietHours(window)
+		if err != nil || !rule.matches(now) {
+			continue
+		}
+		quiet = true
+		rate = window.RefreshRateSecs
+		reasons = []string{"quiet hours " + window.Start + "-" + window.End}
+		// Wake up when the quiet hours end instead of sleeping past them
+		if end, ok := windowEnd(rule, now, time.Duration(rate)*time.Second); ok {
+			rate = int(end.Sub(now).Seconds()) + 1
+			reasons = append(reasons, "until "+end.Format("15:04"))
+		}
+		break
+	}
+
+	if low := rules.LowBattery; low != nil {
+		if sample, ok := telemetryStore.Latest(key); ok && sample.BatteryVoltage > 0 && sample.BatteryVoltage < low.BelowVoltage {
+			if low.RefreshRateSecs > rate {
+				rate = low.RefreshRateSecs
+			}
+			reasons = append(reasons, fmt.Sprintf("low battery %.2fV", sample.BatteryVoltage))
+		}
+	}
+
+	if alert, ok := alertStore.Active(); ok && rules.AlertRefreshRateSecs > 0 {
+		if rules.AlertRefreshRateSecs < rate {
+			rate = rules.AlertRefreshRateSecs
+		}
+		reasons = append(reasons, "alert "+alert.ID)
+	}
+
+	if rules.AlignToViewChanges && !quiet {
+		if until, ok := nextViewChangeFor(device, now, time.Duration(rate)*time.Second); ok {
+			rate = int(until.Seconds()) + 1
+			reasons = append(reasons, "next view change")
+		}
+	}
+
+	if rate < minRate {
+		rate = minRate
+	}
+	return rate, strings.Join(reasons, ", ")
+}
+
+// recordRefreshDecision remembers the refresh rate sent to a device
+func recordRefreshDecision(key string, rate int, reason string) {
+	refreshDecisionsMu.Lock()
+	defer refreshDecisionsMu.Unlock()
+	refreshDecisions[key] = RefreshDecision{Rate: rate, Reason: reason, Time: time.Now()}
+}
+
+// lastRefreshDecision returns the refresh rate last sent to a device
+func lastRefreshDecision(key string) (RefreshDecision, bool) {
+	refreshDecisionsMu.Lock()
+	defer refreshDecisionsMu.Unlock()
+	decision, ok := refreshDecisions[key]
+	return decision, ok
+}
+
+// windowEnd returns the first minute within horizon at which the rule stops matching
+func windowEnd(rule scheduleRule, now time.Time, horizon time.Duration) (time.Time, bool) {
+	for _, t := range rule.changes(now, now.Add(horizon)) {
+		if !rule.matches(t) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// nextViewChangeFor returns how long until the view shown to the device changes, if that
+// happens within horizon. Devices without a playlist follow the global rotation.
+func nextViewChangeFor(device *Device, now time.Time, horizon time.Duration) (time.Duration, bool) {
+	if device != nil {
+		if at, ok, hasPlaylist := deviceRegistry.NextViewChange(device, now, horizon); hasPlaylist {
+			return at.Sub(now), ok
+		}
+	}
+
+	state := currentRotation()
+	if state.pinned != "" || len(views) == 0 {
+		return 0, false
+	}
+	at, ok := nextViewChange(views, state.index, state.lastRotation, now, horizon)
+	if !ok {
+		return 0, false
+	}
+	// The rotation loop applies the change on its next check
+	until := at.Sub(now) + rotationCheckDelay
+	if until > horizon {
+		return 0, false
+	}
+	return until, true
+}
+
+// nextViewChange returns when pickView first chooses a different view than current within
+// horizon. Candidates are the end of the dwell time and the minutes at which a schedule rule
+// of the list starts or stops matching.
+func nextViewChange(list []View, current int, lastRotation, now time.Time, horizon time.Duration) (time.Time, bool) {
+	if current >= len(list) {
+		return time.Time{}, false
+	}
+
+	candidates := []time.Time{}
+	dwell := rotationInterval
+	if d := list[current].Duration; d > 0 {
+		dwell = d
+	}
+	if due := lastRotation.Add(dwell); due.After(now) && due.Sub(now) <= horizon {
+		candidates = append(candidates, due)
+	}
+	for _, view := range list {
+		for _, rule := range view.Schedule {
+			candidates = append(candidates, rule.changes(now, now.Add(horizon))...)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+
+	for _, t := range candidates {
+		if pickView(list, current, lastRotation, t) != current {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -6,18 +6,24 @@ import (
 	"image"
 	"image/color"
 	"image/png"
-	"io"
 	"log"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 func collectData() (*ViewModel, error) {
+	// Normalize into ViewModel
+	return normalizeData(collectSourceData()), nil
+}
+
+// collectSourceData merges the configured JSON files, API endpoints and scripts, in that
+// order (later sources overwrite keys from earlier ones)
+func collectSourceData() map[string]interface{} {
 	rawData := make(map[string]interface{})
 
 	// Collect from JSON files
@@ -56,8 +62,39 @@ func collectData() (*ViewModel, error) {
 		}
 	}
 
-	// Normalize into ViewModel
-	return normalizeData(rawData), nil
+	// Collect from scripts
+	for _, script := range config.DataSources.Scripts {
+		scriptData, err := runScript(script)
+		if err != nil {
+			log.Printf("Warning: Data source script %s failed: %v", script.displayName(), err)
+			continue
+		}
+		for k, v := range scriptData {
+			rawData[k] = v
+		}
+	}
+
+	return rawData
+}
+
+var sourceDataMu sync.Mutex
+var sourceData map[string]interface{}
+
+// refreshSourceData re-runs the global data sources; called once per render cycle
+func refreshSourceData() {
+	data := collectSourceData()
+
+	sourceDataMu.Lock()
+	sourceData = data
+	sourceDataMu.Unlock()
+}
+
+// currentSourceData returns the data collected in the last render cycle
+func currentSourceData() map[string]interface{} {
+	sourceDataMu.Lock()
+	defer sourceDataMu.Unlock()
+
+	return sourceData
 }
 
 func normalizeData(rawData map[string]interface{}) *ViewModel {
@@ -231,7 +268,7 @@ func renderHTML(vm *ViewModel) string {
 %s
   </div>
 </body>
-</html>`, config.Render.Width, config.Render.Height, tailwindCSS, escapeHTML(vm.Title), escapeHTML(vm.Timestamp), gridClass, cardsHTML)
+</html>`, config.Render.Width, config.Render.Height, baseStyles(config.Render.Width, config.Render.Height), escapeHTML(vm.Title), escapeHTML(vm.Timestamp), gridClass, cardsHTML)
 }
 
 func formatValueString(value interface{}) string {
@@ -254,101 +291,42 @@ func escapeHTML(text string) string {
 	return text
 }
 
-func renderToImage(html string, outputPath string) error {
-	// Use Playwright via Node.js script for HTML to PNG conversion
-	scriptPath := filepath.Join(".", "scripts", "playwright-render.js")
-	
-	// Check if script exists
-	if _, err := os.Stat(scriptPath); err != nil {
-		return fmt.Errorf("playwright render script not found at %s: %w. Make sure the script exists and Node.js is installed", scriptPath, err)
-	}
-
-	// Create temporary HTML file
-	tempHTML, err := os.CreateTemp("", "trmnl-render-*.html")
-	if err != nil {
-		return fmt.Errorf("failed to create temp HTML file: %w", err)
-	}
-	defer os.Remove(tempHTML.Name())
-	defer tempHTML.Close()
-
-	// Write HTML to temp file
-	if _, err := tempHTML.WriteString(html); err != nil {
-		return fmt.Errorf("failed to write HTML to temp file: %w", err)
-	}
-	if err := tempHTML.Close(); err != nil {
-		return fmt.Errorf("failed to close temp HTML file: %w", err)
-	}
-
-	// Save intermediate PNG path
-	tempPNG := config.Render.TempPath + ".png"
-	
-	// Ensure output directory exists
-	if err := os.MkdirAll(filepath.Dir(tempPNG), 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
-	}
-
-	// Call Playwright script
-	cmd := exec.Command("node", scriptPath,
-		tempHTML.Name(),
-		tempPNG,
-		strconv.Itoa(config.Render.Width),
-		strconv.Itoa(config.Render.Height),
-	)
-	
-	// Capture stderr for better error messages
-	var stderr strings.Builder
-	cmd.Stderr = &stderr
-	
-	if err := cmd.Run(); err != nil {
-		errMsg := stderr.String()
-		if errMsg == "" {
-			errMsg = err.Error()
-		}
-		return fmt.Errorf("playwright render failed: %s. Make sure Node.js and Playwright are installed (npm install playwright)", errMsg)
-	}
-
-	// Check if PNG was created
-	if _, err := os.Stat(tempPNG); err != nil {
-		return fmt.Errorf("playwright render completed but PNG file not found at %s: %w", tempPNG, err)
-	}
-
-	// Convert to 1-bit monochrome
-	return convertToMonochrome(tempPNG, outputPath)
+func renderToImage(html string, outputPath string, dither DitherSettings) error {
+	return renderProfileImage(html, outputPath, dither, defaultProfile())
 }
 
-func convertToMonochrome(inputPath, outputPath string) error {
-	// Read PNG
-	file, err := os.Open(inputPath)
+// renderProfileImage renders html at the profile's layout size and converts it for the profile
+func renderProfileImage(html string, outputPath string, dither DitherSettings, profile OutputProfile) error {
+	width, height := profile.layoutSize()
+	img, err := getRenderer().Render(html, width, height)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	img, _, err := image.Decode(file)
-	if err != nil {
-		return err
-	}
+	// Convert to 1-bit monochrome (or the profile's grays)
+	return convertToMonochrome(img, outputPath, dither, profile)
+}
 
-	// Resize and convert to 1-bit
+func convertToMonochrome(img image.Image, outputPath string, dither DitherSettings, profile OutputProfile) error {
+	// Resize to a grayscale buffer at the layout size, turn it to fit the panel, then
+	// dither down to the profile's bit depth
 	bounds := img.Bounds()
-	resized := image.NewRGBA(image.Rect(0, 0, config.Render.Width, config.Render.Height))
+	width, height := profile.layoutSize()
+	gray := make([]float64, width*height)
 	
 	// Simple resize (nearest neighbor)
-	for y := 0; y < config.Render.Height; y++ {
-		for x := 0; x < config.Render.Width; x++ {
-			srcX := x * bounds.Dx() / config.Render.Width
-			srcY := y * bounds.Dy() / config.Render.Height
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			srcX := bounds.Min.X + x*bounds.Dx()/width
+			srcY := bounds.Min.Y + y*bounds.Dy()/height
 			r, g, b, _ := img.At(srcX, srcY).RGBA()
-			gray := uint8((r + g + b) / 3 / 256)
-			// Threshold at 128
-			if gray < 128 {
-				resized.Set(x, y, color.RGBA{0, 0, 0, 255})
-			} else {
-				resized.Set(x, y, color.RGBA{255, 255, 255, 255})
-			}
+			gray[y*width+x] = float64((r + g + b) / 3 / 256)
 		}
 	}
 
+	gray, width, height = rotateGray(gray, width, height, profile.Rotate)
+	resized := ditherImage(gray, width, height, resolveDitherSettings(dither), profile.BitDepth)
+
 	// Atomic file replacement: write to temp file first, then rename
 	// This prevents partial reads when TRMNL device fetches the image
 	var tempPath string
@@ -364,23 +342,30 @@ func convertToMonochrome(inputPath, outputPath string) error {
 		finalPath = outputPath
 	}
 
-	// Write as PNG (Go's image/png handles 2-color PNGs well)
 	outputFile, err := os.Create(tempPath)
 	if err != nil {
 		return err
 	}
 	defer outputFile.Close()
 
-	encoder := &png.Encoder{CompressionLevel: png.BestCompression}
-	if err := encoder.Encode(outputFile, resized); err != nil {
+	if filepath.Ext(outputPath) == ".bmp" {
+		// Native 1-bit (or 4bpp grayscale) BMP3 - no ImageMagick needed
+		err = encodeBMP(outputFile, resized, profile.BitDepth)
+	} else if profile.BitDepth > 1 {
+		// A gray palette makes image/png write a 2 or 4-bit PNG
+		encoder := &png.Encoder{CompressionLevel: png.BestCompression}
+		err = encoder.Encode(outputFile, grayPaletted(resized, 1<<profile.BitDepth))
+	} else {
+		// Write as PNG (Go's image/png handles 2-color PNGs well)
+		encoder := &png.Encoder{CompressionLevel: png.BestCompression}
+		err = encoder.Encode(outputFile, resized)
+	}
+	if err != nil {
 		os.Remove(tempPath) // Clean up on error
 		return err
 	}
 	outputFile.Close() // Close before rename
 
-	// Try ImageMagick for true BMP3 1-bit if available
-	tryImageMagickBMP(tempPath)
-
 	// Atomic rename: temp -> final (atomic on POSIX systems)
 	if tempPath != finalPath {
 		if err := os.Rename(tempPath, finalPath); err != nil {
@@ -392,31 +377,21 @@ func convertToMonochrome(inputPath, outputPath string) error {
 	return nil
 }
 
-func tryImageMagickBMP(pngPath string) {
-	// This will be implemented via exec if ImageMagick is available
-	// For now, we'll use PNG which works fine
-	bmpPath := config.Render.OutputPath
-	if bmpPath == "" {
-		return
+// grayPaletted converts an image with evenly spaced gray levels to a paletted image
+func grayPaletted(img *image.Gray, levels int) *image.Paletted {
+	palette := make(color.Palette, levels)
+	for i := range palette {
+		palette[i] = color.Gray{Y: uint8(i * 255 / (levels - 1))}
 	}
-	// Copy PNG as fallback
-	if bmpPath != pngPath {
-		src, err := os.Open(pngPath)
-		if err != nil {
-			return
-		}
-		defer src.Close()
-		dst, err := os.Create(bmpPath)
-		if err != nil {
-			return
-		}
-		defer dst.Close()
-		io.Copy(dst, src)
+	out := image.NewPaletted(img.Rect, palette)
+	step := 255 / (levels - 1)
+	for i, v := range img.Pix {
+		out.Pix[i] = uint8((int(v) + step/2) / step)
 	}
+	return out
 }
 
 func renderAllViews() error {
-	initViews()
 	if len(views) == 0 {
 		return fmt.Errorf("no views configured")
 	}
@@ -426,54 +401,31 @@ func renderAllViews() error {
 	totalHTMLDuration := time.Duration(0)
 	totalImageDuration := time.Duration(0)
 	var lastSize int64
+	hits, misses := 0, 0
+
+	warnUnknownProfiles()
+
+	// Run the global data sources (JSON files, APIs, scripts) once for all views
+	dataStart := time.Now()
+	refreshSourceData()
+	totalDataDuration += time.Since(dataStart)
 
 	// Render each view to its own image file
 	for _, view := range views {
-		// Validate template before rendering (non-blocking warnings)
-		warnings := validateViewBeforeRender(view)
-		if len(warnings) > 0 {
-			log.Printf("⚠️  Template validation warnings for view '%s':", view.Name)
-			for _, warning := range warnings {
-				log.Printf("   - %s", warning)
-			}
-		}
-		
-		// Load view data
-		dataStart := time.Now()
-		_, err := loadViewData(view)
+		result, err := renderView(view)
 		if err != nil {
-			log.Printf("Warning: Failed to load data for view %s: %v", view.Name, err)
+			log.Printf("Warning: %v", err)
 			continue
 		}
-		totalDataDuration += time.Since(dataStart)
-
-		// Render HTML template
-		htmlStart := time.Now()
-		html, err := renderViewHTML(view)
-		if err != nil {
-			log.Printf("Warning: Failed to render HTML for view %s: %v", view.Name, err)
-			continue
+		totalDataDuration += result.DataDuration
+		totalHTMLDuration += result.HTMLDuration
+		totalImageDuration += result.ImageDuration
+		lastSize = result.Size
+		if result.Cached {
+			hits++
+		} else {
+			misses++
 		}
-		totalHTMLDuration += time.Since(htmlStart)
-
-		// Render to image
-		imgStart := time.Now()
-		outputPath := filepath.Join(config.Paths.OutputDir, view.Name+".png")
-		if err := renderToImage(html, outputPath); err != nil {
-			log.Printf("Warning: Failed to render image for view %s: %v", view.Name, err)
-			continue
-		}
-		imgDuration := time.Since(imgStart)
-		totalImageDuration += imgDuration
-
-		// Get file size
-		info, _ := os.Stat(outputPath)
-		if info != nil {
-			lastSize = info.Size()
-		}
-
-		log.Printf("Rendered view %s: html=%v, image=%v, size=%.2f KB",
-			view.Name, time.Since(htmlStart), imgDuration, float64(lastSize)/1024)
 	}
 
 	renderStats = RenderStats{
@@ -482,11 +434,13 @@ func renderAllViews() error {
 		ConversionDuration: totalImageDuration,
 		OutputSize:         lastSize,
 		LastRenderTime:     time.Now(),
+		CacheHits:          hits,
+		CacheMisses:        misses,
 	}
 
 	lastRenderTime = time.Now()
-	log.Printf("All views rendered: total=%v, views=%d",
-		time.Since(start), len(views))
+	log.Printf("All views rendered: total=%v, views=%d, unchanged=%d",
+		time.Since(start), len(views), hits)
 
 	// Render current view to screen.bmp for TRMNL device (atomic replacement)
 	if err := renderCurrentViewToTRMNL(); err != nil {
@@ -497,14 +451,230 @@ func renderAllViews() error {
 	return nil
 }
 
+// viewRenderResult describes a single view render
+type viewRenderResult struct {
+	DataDuration  time.Duration
+	HTMLDuration  time.Duration
+	ImageDuration time.Duration
+	Size          int64
+	Warnings      []string
+	HTML          string // Template views only, for /preview
+	Cached        bool   // The previous image was reused (see RenderCache)
+}
+
+// renderView renders one view to output/<name>.png and output/<name>.bmp and records the
+// outcome for /api/views
+func renderView(view View) (viewRenderResult, error) {
+	start := time.Now()
+	var result viewRenderResult
+	var err error
+	if view.Mashup != nil {
+		result, err = renderMashupView(view)
+	} else {
+		result, err = renderTemplateView(view)
+	}
+	viewStatusStore.Record(view.Name, start, result, err)
+	previewHub.Publish(view, start, result, err)
+	return result, err
+}
+
+// renderTemplateView renders a template view
+func renderTemplateView(view View) (viewRenderResult, error) {
+	var result viewRenderResult
+
+	// Validate template before rendering (non-blocking warnings)
+	result.Warnings = validateViewBeforeRender(view)
+	if len(result.Warnings) > 0 {
+		log.Printf("⚠️  Template validation warnings for view '%s':", view.Name)
+		for _, warning := range result.Warnings {
+			log.Printf("   - %s", warning)
+		}
+	}
+
+	// Load view data
+	dataStart := time.Now()
+	viewData, err := loadViewData(view)
+	if err != nil {
+		return result, fmt.Errorf("failed to load data for view %s: %w", view.Name, err)
+	}
+	result.DataDuration = time.Since(dataStart)
+	if checkViewAlerts(view, viewData.raw) {
+		// Once the view's own images are written, so a cleared alert shows fresh output
+		defer showAlertChange(view)
+	}
+
+	// The result describes the default profile; other profiles only log their outcome
+	for _, profile := range renderProfiles() {
+		profileResult, err := renderTemplateProfile(view, viewData, profile)
+		if profile.Name != defaultProfileName {
+			if err != nil {
+				log.Printf("Warning: %v", err)
+			}
+			continue
+		}
+		result.HTMLDuration = profileResult.HTMLDuration
+		result.ImageDuration = profileResult.ImageDuration
+		result.HTML = profileResult.HTML
+		result.Cached = profileResult.Cached
+		result.Size = profileResult.Size
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// renderTemplateProfile executes the view's template at the profile's layout size and
+// writes output/<name>.png and .bmp (output/<name>@<profile>.* for other profiles)
+func renderTemplateProfile(view View, viewData *ViewData, profile OutputProfile) (viewRenderResult, error) {
+	var result viewRenderResult
+	label := profile.imageName(view.Name)
+
+	// Render HTML template
+	htmlStart := time.Now()
+	viewData.setSize(profile.layoutSize())
+	html, err := executeViewTemplate(view, viewData)
+	if err != nil {
+		return result, fmt.Errorf("failed to render HTML for view %s: %w", label, err)
+	}
+	result.HTMLDuration = time.Since(htmlStart)
+	result.HTML = html
+
+	// Render to image (skipped when the HTML is unchanged)
+	imgStart := time.Now()
+	outputPath := filepath.Join(config.Paths.OutputDir, label+".png")
+	result.Cached, err = renderToImageCached(html, outputPath, view.Dither, profile, viewData.volatile)
+	if err != nil {
+		return result, fmt.Errorf("failed to render image for view %s: %w", label, err)
+	}
+	// BMP copy for devices with their own view playlist
+	bmpPath := filepath.Join(config.Paths.OutputDir, label+".bmp")
+	if err := ensureViewBMP(outputPath, bmpPath, result.Cached, profile); err != nil {
+		log.Printf("Warning: Failed to write BMP for view %s: %v", label, err)
+	}
+	result.ImageDuration = time.Since(imgStart)
+
+	// Get file size
+	if info, _ := os.Stat(outputPath); info != nil {
+		result.Size = info.Size()
+	}
+
+	if result.Cached {
+		log.Printf("View %s unchanged, reused image: html=%v", label, result.HTMLDuration)
+		return result, nil
+	}
+	log.Printf("Rendered view %s: html=%v, image=%v, size=%.2f KB",
+		label, time.Since(htmlStart), result.ImageDuration, float64(result.Size)/1024)
+	return result, nil
+}
+
+// renderMashupView composes a mashup view to output/<name>.png and output/<name>.bmp, and
+// for every active profile to output/<name>@<profile>.*
+func renderMashupView(view View) (viewRenderResult, error) {
+	var result viewRenderResult
+	for _, profile := range renderProfiles() {
+		profileResult, err := renderMashupProfile(view, profile)
+		if profile.Name != defaultProfileName {
+			if err != nil {
+				log.Printf("Warning: %v", err)
+			}
+			continue
+		}
+		result = profileResult
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// renderMashupProfile composes a mashup at the profile's layout size
+func renderMashupProfile(view View, profile OutputProfile) (viewRenderResult, error) {
+	var result viewRenderResult
+	label := profile.imageName(view.Name)
+	parts := prepareMashup(view, profile, &result)
+
+	outputPath := filepath.Join(config.Paths.OutputDir, label+".png")
+	bmpPath := filepath.Join(config.Paths.OutputDir, label+".bmp")
+	key := ""
+	if renderCacheEnabled() {
+		key = mashupCacheKey(view, parts, profile)
+		result.Cached = renderCache.Lookup(outputPath, key)
+	}
+
+	if result.Cached {
+		if err := ensureViewBMP(outputPath, bmpPath, true, profile); err != nil {
+			log.Printf("Warning: Failed to write BMP for view %s: %v", label, err)
+		}
+	} else {
+		img, err := drawMashup(view, parts, profile, &result)
+		if err != nil {
+			return result, err
+		}
+
+		imgStart := time.Now()
+		if err := convertToMonochrome(img, outputPath, view.Dither, profile); err != nil {
+			return result, fmt.Errorf("failed to write image for view %s: %w", label, err)
+		}
+		if key != "" {
+			renderCache.Store(outputPath, key)
+		}
+		if err := ensureViewBMP(outputPath, bmpPath, false, profile); err != nil {
+			log.Printf("Warning: Failed to write BMP for view %s: %v", label, err)
+		}
+		result.ImageDuration += time.Since(imgStart)
+	}
+
+	if info, _ := os.Stat(outputPath); info != nil {
+		result.Size = info.Size()
+	}
+	if result.Cached {
+		log.Printf("Mashup %s unchanged, reused image", label)
+		return result, nil
+	}
+
+	log.Printf("Rendered mashup %s (%s): %d view(s), image=%v, size=%.2f KB",
+		label, view.Mashup.Layout, len(view.Mashup.Children), result.ImageDuration, float64(result.Size)/1024)
+	return result, nil
+}
+
 // renderCurrentViewToTRMNL renders the current rotating view to screen.bmp
 // This is what the TRMNL device fetches via /screen.bmp
 func renderCurrentViewToTRMNL() error {
+	// An active alert preempts the rotation
+	if alert, ok := alertStore.Active(); ok {
+		return renderAlertToTRMNL(alert)
+	}
+
 	view := getCurrentView()
 	if view.Name == "" {
 		return fmt.Errorf("no current view available")
 	}
 
+	// Reuse the per-view image from renderAllViews instead of launching another render
+	viewPNG := filepath.Join(config.Paths.OutputDir, view.Name+".png")
+	if filepath.Ext(config.Render.OutputPath) == ".bmp" {
+		if _, err := os.Stat(viewPNG); err == nil {
+			if err := convertPNGToBMP(viewPNG, config.Render.OutputPath, 1); err != nil {
+				return fmt.Errorf("failed to write %s: %w", config.Render.OutputPath, err)
+			}
+			log.Printf("Updated %s from view '%s' for TRMNL", config.Render.OutputPath, view.Name)
+			return nil
+		}
+	}
+
+	if view.Mashup != nil {
+		img, _, err := composeMashup(view, defaultProfile())
+		if err != nil {
+			return err
+		}
+		if err := convertToMonochrome(img, config.Render.OutputPath, view.Dither, defaultProfile()); err != nil {
+			return fmt.Errorf("failed to render image: %w", err)
+		}
+		log.Printf("Rendered current mashup '%s' to %s for TRMNL", view.Name, config.Render.OutputPath)
+		return nil
+	}
+
 	// Render HTML template
 	html, err := renderViewHTML(view)
 	if err != nil {
@@ -512,7 +682,7 @@ func renderCurrentViewToTRMNL() error {
 	}
 
 	// Render to the configured output path (screen.bmp) with atomic replacement
-	if err := renderToImage(html, config.Render.OutputPath); err != nil {
+	if _, err := renderToImageCached(html, config.Render.OutputPath, view.Dither, defaultProfile(), nil); err != nil {
 		return fmt.Errorf("failed to render image: %w", err)
 	}
 
@@ -526,15 +696,20 @@ func renderOnce() error {
 }
 
 func startScheduler() {
+	configMu.Lock()
 	interval := time.Duration(config.Render.RefreshIntervalMins) * time.Minute
 	ticker := time.NewTicker(interval)
+	renderTicker = ticker // Reset by reloadConfig when the interval changes
+	configMu.Unlock()
 	defer ticker.Stop()
 
 	for range ticker.C {
 		log.Println("Scheduled render triggered")
+		configMu.RLock()
 		if err := renderAllViews(); err != nil {
 			log.Printf("Scheduled render failed: %v", err)
 		}
+		configMu.RUnlock()
 	}
 }
 
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// renderCacheVersion is part of every cache key; bump it when a change to the renderers or
+// the conversion changes the output for the same HTML
+const renderCacheVersion = 1
+
+// RenderCache remembers which HTML and settings produced the image currently in each output
+// file, so a render of unchanged content can keep the file instead of running the renderer
+// and conversion again
+type RenderCache struct {
+	mu      sync.Mutex
+	entries map[string]renderCacheEntry // By output path
+	hits    int64
+	misses  int64
+}
+
+type renderCacheEntry struct {
+	key         string
+	fingerprint string // The file as written; a file changed by someone else is a miss
+}
+
+var renderCache = &RenderCache{entries: make(map[string]renderCacheEntry)}
+
+// renderCacheEnabled reports whether render.cache allows reusing images (default: true)
+func renderCacheEnabled() bool {
+	return config.Render.Cache == nil || *config.Render.Cache
+}
+
+// renderCacheKey hashes everything that determines an image: the HTML, the output profile,
+// the resolved dither settings and the renderer. Volatile values such as the default
+// timestamp are removed from the HTML first, so a view whose data is unchanged keeps its
+// image (and the time it was rendered).
+func renderCacheKey(html string, profile OutputProfile, dither DitherSettings, volatile []string) string {
+	for _, value := range volatile {
+		if value != "" {
+			html = strings.ReplaceAll(html, value, "")
+		}
+	}
+	resolved := resolveDitherSettings(dither)
+	h := sha256.New()
+	fmt.Fprintf(h, "v%d\n%s\n%dx%d/%dbit/%d\n%s/%d/%g/%g\n", renderCacheVersion, getRenderer().Name(),
+		profile.Width, profile.Height, profile.BitDepth, profile.Rotate,
+		resolved.Mode, resolved.Threshold, resolved.Gamma, resolved.Contrast)
+	h.Write([]byte(html))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Lookup reports whether the file at path still holds the image for key
+func (c *RenderCache) Lookup(path, key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[filepath.Clean(path)]
+	if ok && entry.key == key {
+		if info, err := os.Stat(path); err == nil && fileFingerprint(info) == entry.fingerprint {
+			c.hits++
+			return true
+		}
+	}
+	c.misses++
+	return false
+}
+
+// Store records that the file at path was just written from key
+func (c *RenderCache) Store(path, key string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[filepath.Clean(path)] = renderCacheEntry{key: key, fingerprint: fileFingerprint(info)}
+}
+
+// Stats returns the number of hits and misses since the server started
+func (c *RenderCache) Stats() (hits, misses int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// renderToImageCached renders html to outputPath like renderProfileImage, unless the file
+// already holds the image of the same HTML (apart from volatile values) and settings
+func renderToImageCached(html string, outputPath string, dither DitherSettings, profile OutputProfile, volatile []string) (bool, error) {
+	if !renderCacheEnabled() {
+		return false, renderProfileImage(html, outputPath, dither, profile)
+	}
+
+	key := renderCacheKey(html, profile, dither, volatile)
+	if renderCache.Lookup(outputPath, key) {
+		return true, nil
+	}
+	if err := renderProfileImage(html, outputPath, dither, profile); err != nil {
+		return false, err
+	}
+	renderCache.Store(outputPath, key)
+	return false, nil
+}
+
+// ensureViewBMP writes the BMP copy of a view's PNG. When the PNG was reused from the cache
+// the BMP is only rewritten if it is missing or older than the PNG.
+func ensureViewBMP(pngPath, bmpPath string, cached bool, profile OutputProfile) error {
+	if cached {
+		bmpInfo, bmpErr := os.Stat(bmpPath)
+		pngInfo, pngErr := os.Stat(pngPath)
+		if bmpErr == nil && pngErr == nil && !bmpInfo.ModTime().Before(pngInfo.ModTime()) {
+			return nil
+		}
+	}
+	return convertPNGToBMP(pngPath, bmpPath, profile.BitDepth)
+}
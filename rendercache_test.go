@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestRenderCacheRepeatRenders renders a view with the default per-second timestamp in two
+// different seconds: the second render reuses the image until the data changes
+func TestRenderCacheRepeatRenders(t *testing.T) {
+	useTestConfig(t)
+	fake := &fakeRenderer{}
+	useRenderer(t, fake)
+
+	dataPath := filepath.Join(t.TempDir(), "todo.json")
+	if err := os.WriteFile(dataPath, []byte(`{"title": "Todo", "tasks": [{"text": "Water plants"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	view := View{Name: "todo", Template: "./templates/todo.html", DataPath: dataPath}
+
+	first, err := renderTemplateView(view)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Cached {
+		t.Fatal("first render reported a cache hit")
+	}
+
+	// Move to the next second so the timestamp in the HTML differs
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	second, err := renderTemplateView(view)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.HTML == first.HTML {
+		t.Fatal("expected the timestamp to change the HTML")
+	}
+	if !second.Cached {
+		t.Error("repeat render with unchanged data missed the cache")
+	}
+	if calls := len(fake.Calls()); calls != 1 {
+		t.Errorf("renderer called %d times, want 1", calls)
+	}
+
+	// New data is a miss
+	if err := os.WriteFile(dataPath, []byte(`{"title": "Todo", "tasks": [{"text": "Feed the cat"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	third, err := renderTemplateView(view)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.Cached {
+		t.Error("render with changed data hit the cache")
+	}
+	if hits, misses := renderCache.Stats(); hits != 1 || misses != 2 {
+		t.Errorf("cache stats = %d hits, %d misses, want 1 and 2", hits, misses)
+	}
+}
+
+func TestRenderCacheKeyKeepsDataTimestamps(t *testing.T) {
+	profile := OutputProfile{Name: defaultProfileName, Width: 800, Height: 480, BitDepth: 1}
+	a := renderCacheKey("<p>2026-10-17 07:00:00</p>", profile, DitherSettings{}, []string{"2026-10-17 07:00:00"})
+	b := renderCacheKey("<p>2026-10-17 07:00:01</p>", profile, DitherSettings{}, []string{"2026-10-17 07:00:01"})
+	if a != b {
+		t.Error("volatile values changed the key")
+	}
+	// Without the volatile list (a timestamp from the data) they differ
+	c := renderCacheKey("<p>2026-10-17 07:00:01</p>", profile, DitherSettings{}, nil)
+	if a == c {
+		t.Error("a timestamp from the data did not change the key")
+	}
+}
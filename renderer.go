@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+)
+
+// Renderer turns a complete HTML document into an image of the given size
+type Renderer interface {
+	Name() string
+	Render(html string, width, height int) (image.Image, error)
+}
+
+const (
+	rendererAuto       = "auto"
+	rendererPlaywright = "playwright"
+	rendererGo         = "go"
+)
+
+var activeRenderer Renderer
+var activeRendererMu sync.Mutex
+
+// getRenderer returns the renderer selected by render.renderer, choosing on first use
+func getRenderer() Renderer {
+	activeRendererMu.Lock()
+	defer activeRendererMu.Unlock()
+
+	if activeRenderer == nil {
+		activeRenderer = selectRenderer(config.Render.Renderer)
+		log.Printf("Using %s renderer", activeRenderer.Name())
+	}
+	return activeRenderer
+}
+
+// setRenderer replaces the active renderer (used to inject alternative backends)
+func setRenderer(r Renderer) {
+	activeRendererMu.Lock()
+	defer activeRendererMu.Unlock()
+
+	activeRenderer = r
+}
+
+func validateRendererName(name string) error {
+	switch name {
+	case "", rendererAuto, rendererPlaywright, rendererGo:
+		return nil
+	}
+	return fmt.Errorf("unknown renderer '%s' (expected auto, playwright or go)", name)
+}
+
+// selectRenderer resolves "auto" to Playwright when Node.js and the worker script are
+// available, falling back to the pure-Go renderer otherwise
+func selectRenderer(name string) Renderer {
+	switch name {
+	case rendererPlaywright:
+		return PlaywrightRenderer{}
+	case rendererGo:
+		return GoRenderer{}
+	}
+
+	if _, err := exec.LookPath("node"); err != nil {
+		log.Printf("Warning: Node.js not found, falling back to the pure-Go renderer")
+		return GoRenderer{}
+	}
+	if _, err := os.Stat(getBrowserWorker().scriptPath); err != nil {
+		log.Printf("Warning: Playwright worker script not found, falling back to the pure-Go renderer")
+		return GoRenderer{}
+	}
+	return PlaywrightRenderer{}
+}
+
+// PlaywrightRenderer renders through the persistent headless Chromium worker
+type PlaywrightRenderer struct{}
+
+func (PlaywrightRenderer) Name() string { return rendererPlaywright }
+
+func (PlaywrightRenderer) Render(html string, width, height int) (image.Image, error) {
+	tempDir := filepath.Dir(config.Render.TempPath)
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// Unique intermediate PNG so concurrent renders don't collide
+	tempFile, err := os.CreateTemp(tempDir, "render-*.png")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp PNG file: %w", err)
+	}
+	tempPNG := tempFile.Name()
+	tempFile.Close()
+	defer os.Remove(tempPNG)
+
+	absPNG, err := filepath.Abs(tempPNG)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := getBrowserWorker().Render(html, absPNG, width, height); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(tempPNG)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("playwright render completed but PNG could not be read: %w", err)
+	}
+	return img, nil
+}
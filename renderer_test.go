@@ -0,0 +1,160 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRenderer records what the pipeline asks it to render and returns an image whose left
+// half is black and right half white
+type fakeRenderer struct {
+	mu    sync.Mutex
+	calls []fakeRenderCall
+}
+
+type fakeRenderCall struct {
+	html          string
+	width, height int
+}
+
+func (*fakeRenderer) Name() string { return "fake" }
+
+func (f *fakeRenderer) Render(html string, width, height int) (image.Image, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, fakeRenderCall{html, width, height})
+	f.mu.Unlock()
+
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := width / 2; x < width; x++ {
+			img.SetGray(x, y, color.Gray{Y: 255})
+		}
+	}
+	return img, nil
+}
+
+func (f *fakeRenderer) Calls() []fakeRenderCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]fakeRenderCall(nil), f.calls...)
+}
+
+// useRenderer injects r for the duration of the test
+func useRenderer(t *testing.T, r Renderer) {
+	t.Helper()
+	activeRendererMu.Lock()
+	previous := activeRenderer
+	activeRendererMu.Unlock()
+
+	setRenderer(r)
+	t.Cleanup(func() { setRenderer(previous) })
+}
+
+// useTestConfig gives the test an 800x480 config writing to a temporary output directory,
+// and restores the global config and render cache afterwards
+func useTestConfig(t *testing.T) {
+	t.Helper()
+	saved := config
+	savedCache := renderCache
+	t.Cleanup(func() {
+		config = saved
+		renderCache = savedCache
+	})
+
+	config = Config{}
+	config.Render.Width = 800
+	config.Render.Height = 480
+	config.Paths.OutputDir = t.TempDir()
+	renderCache = &RenderCache{entries: make(map[string]renderCacheEntry)}
+}
+
+func TestRenderTemplateViewUsesInjectedRenderer(t *testing.T) {
+	useTestConfig(t)
+	fake := &fakeRenderer{}
+	useRenderer(t, fake)
+
+	view := View{Name: "todo", Template: "./templates/todo.html", DataPath: "./data/todo.json"}
+	result, err := renderTemplateView(view)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("renderer called %d times, want 1", len(calls))
+	}
+	if calls[0].width != 800 || calls[0].height != 480 {
+		t.Errorf("rendered at %dx%d, want 800x480", calls[0].width, calls[0].height)
+	}
+	if calls[0].html != result.HTML || !strings.Contains(result.HTML, "<body") {
+		t.Errorf("renderer did not receive the template's HTML")
+	}
+
+	// The PNG is the converted 1-bit image of what the renderer returned
+	file, err := os.Open(filepath.Join(config.Paths.OutputDir, "todo.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 800 || img.Bounds().Dy() != 480 {
+		t.Errorf("output is %v, want 800x480", img.Bounds())
+	}
+	if gray := color.GrayModel.Convert(img.At(10, 240)).(color.Gray); gray.Y != 0 {
+		t.Errorf("left half is %d, want black", gray.Y)
+	}
+	if gray := color.GrayModel.Convert(img.At(790, 240)).(color.Gray); gray.Y != 255 {
+		t.Errorf("right half is %d, want white", gray.Y)
+	}
+
+	if _, err := os.Stat(filepath.Join(config.Paths.OutputDir, "todo.bmp")); err != nil {
+		t.Errorf("BMP copy missing: %v", err)
+	}
+}
+
+func TestRenderProfileImageRotates(t *testing.T) {
+	useTestConfig(t)
+	fake := &fakeRenderer{}
+	useRenderer(t, fake)
+
+	profile := OutputProfile{Name: "portrait", Width: 800, Height: 480, BitDepth: 1, Rotate: 90}
+	outputPath := filepath.Join(config.Paths.OutputDir, "view@portrait.png")
+	if err := renderProfileImage("<html><body></body></html>", outputPath, DitherSettings{}, profile); err != nil {
+		t.Fatal(err)
+	}
+
+	// Laid out in portrait...
+	calls := fake.Calls()
+	if len(calls) != 1 || calls[0].width != 480 || calls[0].height != 800 {
+		t.Fatalf("renderer calls = %+v, want one at 480x800", calls)
+	}
+
+	// ...and turned clockwise to the panel: the black left half ends up on top
+	file, err := os.Open(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 800 || img.Bounds().Dy() != 480 {
+		t.Fatalf("output is %v, want 800x480", img.Bounds())
+	}
+	if gray := color.GrayModel.Convert(img.At(400, 10)).(color.Gray); gray.Y != 0 {
+		t.Errorf("top is %d, want black", gray.Y)
+	}
+	if gray := color.GrayModel.Convert(img.At(400, 470)).(color.Gray); gray.Y != 255 {
+		t.Errorf("bottom is %d, want white", gray.Y)
+	}
+}
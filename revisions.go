@@ -0,0 +1,78 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ImageRevisions caches a content hash per image file, recomputed only when the file's
+// modification time or size changes. The hash is the /api/display filename and the ETag.
+type ImageRevisions struct {
+	mu      sync.Mutex
+	entries map[string]imageRevision // By path
+}
+
+type imageRevision struct {
+	fingerprint string
+	hash        string
+}
+
+var imageRevisions = &ImageRevisions{entries: make(map[string]imageRevision)}
+
+// Get returns the revision of the file's current content
+func (c *ImageRevisions) Get(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	fingerprint := fileFingerprint(info)
+	key := filepath.Clean(path)
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && entry.fingerprint == fingerprint {
+		return entry.hash, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	hash := hex.EncodeToString(h.Sum(nil))[:16]
+
+	c.mu.Lock()
+	c.entries[key] = imageRevision{fingerprint: fingerprint, hash: hash}
+	c.mu.Unlock()
+	return hash, nil
+}
+
+// imageContentType returns the Content-Type of an image extension
+func imageContentType(ext string) string {
+	if ext == ".bmp" {
+		return "image/bmp"
+	}
+	return "image/png"
+}
+
+// serveImageFile serves an image with its revision as ETag, so clients sending
+// If-None-Match with the current revision get 304 Not Modified
+func serveImageFile(w http.ResponseWriter, r *http.Request, path string) {
+	if revision, err := imageRevisions.Get(path); err == nil {
+		w.Header().Set("ETag", `"`+revision+`"`)
+	}
+	// Clients may keep the image but must revalidate it on every fetch
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Type", imageContentType(filepath.Ext(path)))
+	http.ServeFile(w, r, path)
+}
@@ -1,17 +1,126 @@
 package main
 
 import (
+	"fmt"
+	"log"
+	"sync"
 	"time"
 )
 
+// rotationMu guards currentViewIndex, lastRotationTime and pinnedView. The rotation loop and
+// the admin API hold it while they change the view; HTTP handlers read through currentRotation.
+var rotationMu sync.Mutex
+
+// pinnedView keeps the rotation on one view until it is unpinned (empty = rotate normally)
+var pinnedView string
+
+// rotationState is a consistent copy of the global rotation
+type rotationState struct {
+	view         View
+	index        int
+	lastRotation time.Time
+	pinned       string // Empty unless a configured view is pinned
+}
+
+// currentRotation reads the rotation under rotationMu. Code that already holds the lock
+// (the rotation loop, skipView, pinView) uses getCurrentView instead.
+func currentRotation() rotationState {
+	rotationMu.Lock()
+	defer rotationMu.Unlock()
+
+	state := rotationState{view: getCurrentView(), index: currentViewIndex, lastRotation: lastRotationTime}
+	if _, ok := pinnedIndex(); ok {
+		state.pinned = pinnedView
+	}
+	return state
+}
+
 func startViewRotation() {
 	ticker := time.NewTicker(1 * time.Minute) // Check every minute
 	defer ticker.Stop()
 
+	checkRotation() // Pick the scheduled view right away after startup
 	for range ticker.C {
-		if shouldRotate() {
-			rotateView()
+		checkRotation()
+	}
+}
+
+// checkRotation rotates if needed and refreshes screen.bmp with the new view
+func checkRotation() {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	rotationMu.Lock()
+	defer rotationMu.Unlock()
+
+	if !shouldRotate() {
+		return
+	}
+	rotateView()
+	if err := renderCurrentViewToTRMNL(); err != nil {
+		log.Printf("Warning: Failed to update screen after rotation: %v", err)
+	}
+}
+
+// pinnedIndex returns the index of the pinned view, if one is pinned and still configured
+func pinnedIndex() (int, bool) {
+	if pinnedView == "" {
+		return 0, false
+	}
+	for i, view := range views {
+		if view.Name == pinnedView {
+			return i, true
 		}
 	}
+	return 0, false
+}
+
+// skipView moves to the next eligible view right away, ignoring the dwell time. Skipping
+// also releases a pinned view.
+func skipView() View {
+	rotationMu.Lock()
+	defer rotationMu.Unlock()
+
+	pinnedView = ""
+	if len(views) > 0 {
+		// A zero last rotation time counts as an elapsed dwell time; views outside their
+		// schedule are still skipped
+		currentViewIndex = pickView(views, currentViewIndex, time.Time{}, time.Now())
+		lastRotationTime = time.Now()
+		log.Printf("Skipped to view: %s (index %d)", views[currentViewIndex].Name, currentViewIndex)
+	}
+	if err := renderCurrentViewToTRMNL(); err != nil {
+		log.Printf("Warning: Failed to update screen after skip: %v", err)
+	}
+	return getCurrentView()
 }
 
+// pinView shows the named view and keeps it on screen until unpinned; an empty name unpins
+func pinView(name string) error {
+	rotationMu.Lock()
+	defer rotationMu.Unlock()
+
+	if name == "" {
+		if pinnedView != "" {
+			log.Printf("Unpinned view: %s", pinnedView)
+		}
+		pinnedView = ""
+		lastRotationTime = time.Now()
+		return nil
+	}
+
+	previous := pinnedView
+	pinnedView = name
+	index, ok := pinnedIndex()
+	if !ok {
+		pinnedView = previous
+		return fmt.Errorf("unknown view '%s' (only views in the rotation can be pinned)", name)
+	}
+	currentViewIndex = index
+	lastRotationTime = time.Now()
+	log.Printf("Pinned view: %s", name)
+
+	if err := renderCurrentViewToTRMNL(); err != nil {
+		log.Printf("Warning: Failed to update screen after pinning: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,462 @@
+package main
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScheduleRule restricts when a view may be shown. A rule is either a cron expression
+// (optionally held for durationMinutes after each match) or a weekday/time window.
+type ScheduleRule struct {
+	Cron         string   `json:"cron,omitempty"`            // "minute hour day-of-month month day-of-week"
+	DurationMins int      `json:"durationMinutes,omitempty"` // cron: stay eligible this long after each match
+	Days         []string `json:"days,omitempty"`            // "mon".."sun" or ranges like "mon-fri" (empty = every day)
+	Start        string   `json:"start,omitempty"`           // Window start, "HH:MM"
+	End          string   `json:"end,omitempty"`             // Window end, "HH:MM" (exclusive, may wrap past midnight)
+}
+
+// maxCronDuration bounds durationMinutes so matching stays cheap (one week)
+const maxCronDuration = 7 * 24 * 60
+
+// scheduleRule is a validated, ready to match ScheduleRule
+type scheduleRule struct {
+	cron     *cronExpr
+	duration time.Duration
+	days     [7]bool // Indexed by time.Weekday
+	start    int     // Minutes since midnight
+	end      int
+}
+
+var weekdayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+// compileSchedule validates the rules of one view
+func compileSchedule(rules []ScheduleRule) ([]scheduleRule, error) {
+	errors := []string{}
+	compiled := make([]scheduleRule, 0, len(rules))
+
+	for i, rule := range rules {
+		c, err := compileScheduleRule(rule)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("schedule[%d]: %v", i, err))
+			continue
+		}
+		compiled = append(compiled, c)
+	}
+
+	if len(errors) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errors, "; "))
+	}
+	return compiled, nil
+}
+
+func compileScheduleRule(rule ScheduleRule) (scheduleRule, error) {
+	var c scheduleRule
+	hasWindow := rule.Start != "" || rule.End != "" || len(rule.Days) > 0
+
+	if rule.Cron != "" {
+		if hasWindow {
+			return c, fmt.Errorf("use either cron or days/start/end, not both")
+		}
+		expr, err := parseCron(rule.Cron)
+		if err != nil {
+			return c, err
+		}
+		if rule.DurationMins < 0 || rule.DurationMins > maxCronDuration {
+			return c, fmt.Errorf("durationMinutes must be between 0 and %d", maxCronDuration)
+		}
+		c.cron = expr
+		c.duration = time.Duration(rule.DurationMins) * time.Minute
+		return c, nil
+	}
+
+	if !hasWindow {
+		return c, fmt.Errorf("either cron or days/start/end is required")
+	}
+	if rule.DurationMins != 0 {
+		return c, fmt.Errorf("durationMinutes only applies to cron rules")
+	}
+
+	if len(rule.Days) == 0 {
+		c.days = [7]bool{true, true, true, true, true, true, true}
+	}
+	for _, day := range rule.Days {
+		lo, hi, err := parseDayRange(day)
+		if err != nil {
+			return c, err
+		}
+		for d := lo; ; d = (d + 1) % 7 {
+			c.days[d] = true
+			if d == hi {
+				break
+			}
+		}
+	}
+
+	start, end := "00:00", "24:00"
+	if rule.Start != "" {
+		start = rule.Start
+	}
+	if rule.End != "" {
+		end = rule.End
+	}
+	var err error
+	if c.start, err = parseClock(start); err != nil {
+		return c, fmt.Errorf("start: %w", err)
+	}
+	if c.end, err = parseClock(end); err != nil {
+		return c, fmt.Errorf("end: %w", err)
+	}
+	if c.start == c.end {
+		return c, fmt.Errorf("start and end must differ")
+	}
+	return c, nil
+}
+
+// parseDayRange parses "mon" or "mon-fri" (ranges may wrap, e.g. "fri-mon")
+func parseDayRange(text string) (int, int, error) {
+	parts := strings.SplitN(strings.ToLower(strings.TrimSpace(text)), "-", 2)
+	lo, ok := weekdayNames[parts[0]]
+	if !ok {
+		return 0, 0, fmt.Errorf("unknown day '%s' (expected mon, tue, wed, thu, fri, sat or sun)", text)
+	}
+	hi := lo
+	if len(parts) == 2 {
+		if hi, ok = weekdayNames[parts[1]]; !ok {
+			return 0, 0, fmt.Errorf("unknown day '%s' (expected mon, tue, wed, thu, fri, sat or sun)", text)
+		}
+	}
+	return lo, hi, nil
+}
+
+// parseClock parses "HH:MM" into minutes since midnight ("24:00" is allowed as an end)
+func parseClock(text string) (int, error) {
+	t, err := time.Parse("15:04", text)
+	if err == nil {
+		return t.Hour()*60 + t.Minute(), nil
+	}
+	if text == "24:00" {
+		return 24 * 60, nil
+	}
+	return 0, fmt.Errorf("invalid time '%s' (expected HH:MM)", text)
+}
+
+// matches reports whether the rule allows showing the view at now
+func (r scheduleRule) matches(now time.Time) bool {
+	if r.cron != nil {
+		t := now.Truncate(time.Minute)
+		_, ok := r.cron.prev(t, t.Add(-r.duration))
+		return ok
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	day := int(now.Weekday())
+	if r.start < r.end {
+		return r.days[day] && minute >= r.start && minute < r.end
+	}
+	// Window wraps past midnight: the part after midnight belongs to the previous day
+	if minute >= r.start {
+		return r.days[day]
+	}
+	return minute < r.end && r.days[(day+6)%7]
+}
+
+// changes returns the minutes in (from, to] at which the rule may start or stop matching,
+// in order. Callers check matches at these times instead of at every minute.
+func (r scheduleRule) changes(from, to time.Time) []time.Time {
+	times := []time.Time{}
+	if r.cron != nil {
+		// A match turns the rule on and, unless another match follows within the duration,
+		// off again one minute after the duration
+		t, ok := r.cron.next(from.Add(-r.duration), to)
+		for ok {
+			next, more := r.cron.next(t.Add(time.Minute), to)
+			if t.After(from) {
+				times = append(times, t)
+			}
+			if off := t.Add(r.duration + time.Minute); (!more || next.After(off)) && off.After(from) && !off.After(to) {
+				times = append(times, off)
+			}
+			t, ok = next, more
+		}
+		return times
+	}
+
+	// Windows change at their start and end, and at midnight when the day changes
+	loc := from.Location()
+	for day := startOfDay(from); !day.After(to); day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc) {
+		for _, minute := range []int{0, r.start, r.end} {
+			t := time.Date(day.Year(), day.Month(), day.Day(), 0, minute, 0, 0, loc)
+			if t.After(from) && !t.After(to) {
+				times = append(times, t)
+			}
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	return times
+}
+
+// scheduled reports whether the view has schedule rules at all
+func (v View) scheduled() bool {
+	return len(v.Schedule) > 0
+}
+
+// activeAt reports whether any of the view's schedule rules matches now
+func (v View) activeAt(now time.Time) bool {
+	for _, rule := range v.Schedule {
+		if rule.matches(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// eligibleViews returns the indices of the views that may be shown at now. Scheduled views
+// whose rules match take precedence; unscheduled views fill the time in between.
+func eligibleViews(list []View, now time.Time) []int {
+	active := []int{}
+	unscheduled := []int{}
+	for i, view := range list {
+		if !view.scheduled() {
+			unscheduled = append(unscheduled, i)
+		} else if view.activeAt(now) {
+			active = append(active, i)
+		}
+	}
+	if len(active) > 0 {
+		return active
+	}
+	return unscheduled
+}
+
+// pickView returns the index of the view to display: the current one while it is eligible
+// and its dwell time hasn't elapsed, otherwise the next eligible view in config order. With
+// nothing eligible the current view stays on screen.
+func pickView(list []View, current int, lastRotation, now time.Time) int {
+	eligible := eligibleViews(list, now)
+	if len(eligible) == 0 || len(list) == 0 {
+		return current
+	}
+
+	isEligible := false
+	for _, i := range eligible {
+		if i == current {
+			isEligible = true
+			break
+		}
+	}
+
+	if isEligible {
+		if len(eligible) == 1 {
+			return current
+		}
+		dwell := rotationInterval
+		if d := list[current].Duration; d > 0 {
+			dwell = d
+		}
+		if now.Sub(lastRotation) < dwell {
+			return current
+		}
+	}
+
+	// Next eligible view after the current one, wrapping around
+	for _, i := range eligible {
+		if i > current {
+			return i
+		}
+	}
+	return eligible[0]
+}
+
+// cronExpr is a parsed 5-field cron expression; each field is a bitmask of allowed values
+type cronExpr struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+func parseCron(text string) (*cronExpr, error) {
+	fields := strings.Fields(text)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron '%s' must have 5 fields (minute hour day-of-month month day-of-week)", text)
+	}
+
+	expr := &cronExpr{}
+	var err error
+	if expr.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("cron minute: %w", err)
+	}
+	if expr.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("cron hour: %w", err)
+	}
+	if expr.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("cron day-of-month: %w", err)
+	}
+	if expr.month, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("cron month: %w", err)
+	}
+	if expr.dow, err = parseCronField(fields[4], 0, 7, weekdayNames); err != nil {
+		return nil, fmt.Errorf("cron day-of-week: %w", err)
+	}
+	if expr.dow&(1<<7) != 0 {
+		expr.dow |= 1 // 7 is also Sunday
+	}
+	// As in Vixie cron, a day field starting with "*" ("*/2" included) or covering its whole
+	// range ("1-31", "0-7") is unrestricted, so the other day field alone decides
+	expr.domStar = strings.HasPrefix(fields[2], "*") || expr.dom == cronRange(1, 31)
+	expr.dowStar = strings.HasPrefix(fields[4], "*") || expr.dow&cronRange(0, 6) == cronRange(0, 6)
+	return expr, nil
+}
+
+// parseCronField parses lists of values, ranges and steps: "*", "*/15", "1-5", "mon-fri", "0,30"
+func parseCronField(field string, min, max int, names map[string]int) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if slash := strings.IndexByte(part, '/'); slash != -1 {
+			n, err := strconv.Atoi(part[slash+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in '%s'", part)
+			}
+			step = n
+			part = part[:slash]
+		}
+
+		lo, hi := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = parseCronValue(bounds[0], names); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = parseCronValue(bounds[1], names); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				hi = max // "5/15" means starting at 5
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("'%s' is out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+// cronRange returns the mask of the values lo..hi
+func cronRange(lo, hi int) uint64 {
+	return (1<<uint(hi+1) - 1) &^ (1<<uint(lo) - 1)
+}
+
+func parseCronValue(text string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(text)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value '%s'", text)
+	}
+	return v, nil
+}
+
+// matches reports whether t falls on the expression (minute resolution). As in standard
+// cron, a restricted day-of-month and day-of-week match if either one does.
+func (c *cronExpr) matches(t time.Time) bool {
+	if c.minute&(1<<uint(t.Minute())) == 0 || c.hour&(1<<uint(t.Hour())) == 0 {
+		return false
+	}
+	return c.matchesDay(t)
+}
+
+// matchesDay reports whether the month, day-of-month and day-of-week of t match
+func (c *cronExpr) matchesDay(t time.Time) bool {
+	if c.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// prev returns the last matching minute at or before t, if it is not before earliest.
+// Days and hours without a match are skipped as a whole, so a week takes a few hundred steps.
+func (c *cronExpr) prev(t, earliest time.Time) (time.Time, bool) {
+	t = t.Truncate(time.Minute)
+	for !t.Before(earliest) {
+		y, mo, d := t.Date()
+		loc := t.Location()
+		var n time.Time
+		if !c.matchesDay(t) {
+			n = startOfDay(t).Add(-time.Minute)
+		} else if hours := c.hour & cronRange(0, t.Hour()); hours == 0 {
+			n = startOfDay(t).Add(-time.Minute)
+		} else if h := bits.Len64(hours) - 1; h < t.Hour() {
+			n = time.Date(y, mo, d, h, 59, 0, 0, loc)
+		} else if minutes := c.minute & cronRange(0, t.Minute()); minutes == 0 {
+			n = time.Date(y, mo, d, h, 0, 0, 0, loc).Add(-time.Minute)
+		} else if m := bits.Len64(minutes) - 1; m < t.Minute() {
+			n = time.Date(y, mo, d, h, m, 0, 0, loc)
+		} else {
+			return t, true
+		}
+		// Daylight saving transitions can move the wall clock forward; always make progress
+		if !n.Before(t) {
+			n = t.Add(-time.Minute)
+		}
+		t = n
+	}
+	return time.Time{}, false
+}
+
+// next returns the first matching minute at or after t, if it is not after latest
+func (c *cronExpr) next(t, latest time.Time) (time.Time, bool) {
+	if !t.Equal(t.Truncate(time.Minute)) {
+		t = t.Truncate(time.Minute).Add(time.Minute)
+	}
+	for !t.After(latest) {
+		y, mo, d := t.Date()
+		loc := t.Location()
+		var n time.Time
+		if !c.matchesDay(t) {
+			n = time.Date(y, mo, d+1, 0, 0, 0, 0, loc)
+		} else if hours := c.hour & cronRange(t.Hour(), 23); hours == 0 {
+			n = time.Date(y, mo, d+1, 0, 0, 0, 0, loc)
+		} else if h := bits.TrailingZeros64(hours); h > t.Hour() {
+			n = time.Date(y, mo, d, h, 0, 0, 0, loc)
+		} else if minutes := c.minute & cronRange(t.Minute(), 59); minutes == 0 {
+			n = time.Date(y, mo, d, h+1, 0, 0, 0, loc)
+		} else if m := bits.TrailingZeros64(minutes); m > t.Minute() {
+			n = time.Date(y, mo, d, h, m, 0, 0, loc)
+		} else {
+			return t, true
+		}
+		if !n.After(t) {
+			n = t.Add(time.Minute)
+		}
+		t = n
+	}
+	return time.Time{}, false
+}
+
+// startOfDay returns midnight of t's day in t's location
+func startOfDay(t time.Time) time.Time {
+	y, mo, d := t.Date()
+	return time.Date(y, mo, d, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func mustCron(t *testing.T, text string) *cronExpr {
+	t.Helper()
+	expr, err := parseCron(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return expr
+}
+
+func TestCronDayFields(t *testing.T) {
+	at := func(day int) time.Time { return time.Date(2026, time.October, day, 8, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		cron string
+		day  int // October 2026: the 19th and 26th are Mondays
+		want bool
+	}{
+		// A stepped "*" is unrestricted: odd days that are also Mondays
+		{"0 8 */2 * 1", 19, true},
+		{"0 8 */2 * 1", 26, false},
+		{"0 8 */2 * 1", 27, false},
+		// A full range is unrestricted too
+		{"0 8 1-31 * mon", 19, true},
+		{"0 8 1-31 * mon", 20, false},
+		{"0 8 * * 0-7", 20, true},
+		// Two restricted fields match if either does
+		{"0 8 1,15 * 1", 15, true},
+		{"0 8 1,15 * 1", 19, true},
+		{"0 8 1,15 * 1", 20, false},
+	}
+	for _, tt := range tests {
+		if got := mustCron(t, tt.cron).matches(at(tt.day)); got != tt.want {
+			t.Errorf("%q on October %d = %v, want %v", tt.cron, tt.day, got, tt.want)
+		}
+	}
+}
+
+var scheduleTestCrons = []string{
+	"* * * * *",
+	"*/15 9-17 * * mon-fri",
+	"30 7 * * *",
+	"0 0 1 * *",
+	"0 8 */2 * 1",
+	"5,50 */5 10-20 feb-apr *",
+}
+
+// TestCronPrevNext compares the skipping searches with a minute-by-minute scan
+func TestCronPrevNext(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		loc = time.UTC
+	}
+	// Spans the end of daylight saving time in Europe
+	start := time.Date(2026, time.October, 20, 0, 0, 0, 0, loc)
+	end := start.Add(14 * 24 * time.Hour)
+
+	for _, text := range scheduleTestCrons {
+		expr := mustCron(t, text)
+		for now := start; now.Before(end); now = now.Add(97 * time.Minute) {
+			earliest := now.Add(-3 * 24 * time.Hour)
+			wantPrev, wantPrevOK := time.Time{}, false
+			for m := now; !m.Before(earliest); m = m.Add(-time.Minute) {
+				if expr.matches(m) {
+					wantPrev, wantPrevOK = m, true
+					break
+				}
+			}
+			if got, ok := expr.prev(now, earliest); ok != wantPrevOK || !got.Equal(wantPrev) {
+				t.Fatalf("%q prev(%v) = %v %v, want %v %v", text, now, got, ok, wantPrev, wantPrevOK)
+			}
+
+			latest := now.Add(3 * 24 * time.Hour)
+			wantNext, wantNextOK := time.Time{}, false
+			for m := now; !m.After(latest); m = m.Add(time.Minute) {
+				if expr.matches(m) {
+					wantNext, wantNextOK = m, true
+					break
+				}
+			}
+			if got, ok := expr.next(now, latest); ok != wantNextOK || !got.Equal(wantNext) {
+				t.Fatalf("%q next(%v) = %v %v, want %v %v", text, now, got, ok, wantNext, wantNextOK)
+			}
+		}
+	}
+}
+
+// TestScheduleRuleChanges checks that every minute at which a rule flips is reported
+func TestScheduleRuleChanges(t *testing.T) {
+	rules := []ScheduleRule{
+		{Start: "22:00", End: "06:30", Days: []string{"fri-sun"}},
+		{Start: "09:15", End: "10:00"},
+		{Cron: "0 8 * * mon-fri", DurationMins: 90},
+		{Cron: "*/20 * * * *", DurationMins: 30},
+		{Cron: "45 23 * * *"},
+	}
+	from := time.Date(2026, time.October, 16, 5, 17, 30, 0, time.UTC)
+	to := from.Add(4 * 24 * time.Hour)
+
+	for i, raw := range rules {
+		rule, err := compileScheduleRule(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reported := make(map[time.Time]bool)
+		for _, c := range rule.changes(from, to) {
+			reported[c] = true
+		}
+		prev := rule.matches(from)
+		for m := from.Truncate(time.Minute).Add(time.Minute); !m.After(to); m = m.Add(time.Minute) {
+			cur := rule.matches(m)
+			if cur != prev && !reported[m] {
+				t.Errorf("rule %d: change at %v not reported", i, m)
+			}
+			prev = cur
+		}
+	}
+}
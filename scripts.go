@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ScriptSource is a command whose stdout (a JSON object) is merged into the data sources.
+// In config.json it is either a plain command string or an object with the fields below.
+type ScriptSource struct {
+	Name        string            `json:"name,omitempty"` // Defaults to the command
+	Command     string            `json:"command"`
+	Args        []string          `json:"args,omitempty"`
+	Dir         string            `json:"dir,omitempty"`            // Working directory (default: server directory)
+	Env         map[string]string `json:"env,omitempty"`            // Added to the server's environment
+	TimeoutSecs int               `json:"timeoutSeconds,omitempty"` // 0 = defaultScriptTimeout
+}
+
+// defaultScriptTimeout bounds scripts that don't set timeoutSeconds
+const defaultScriptTimeout = 30 * time.Second
+
+// maxScriptStderr caps how much stderr is kept for /api/status
+const maxScriptStderr = 4096
+
+func (s *ScriptSource) UnmarshalJSON(data []byte) error {
+	var command string
+	if err := json.Unmarshal(data, &command); err == nil {
+		*s = ScriptSource{Command: command}
+		return nil
+	}
+
+	type plain ScriptSource
+	var source plain
+	if err := json.Unmarshal(data, &source); err != nil {
+		return fmt.Errorf("script must be a command string or an object: %w", err)
+	}
+	*s = ScriptSource(source)
+	return nil
+}
+
+func (s ScriptSource) displayName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return strings.TrimSpace(s.Command + " " + strings.Join(s.Args, " "))
+}
+
+func (s ScriptSource) timeout() time.Duration {
+	if s.TimeoutSecs > 0 {
+		return time.Duration(s.TimeoutSecs) * time.Second
+	}
+	return defaultScriptTimeout
+}
+
+// validateScriptSources checks the configured scripts before the server starts
+func validateScriptSources(sources []ScriptSource) error {
+	errors := []string{}
+	for i, source := range sources {
+		if strings.TrimSpace(source.Command) == "" {
+			errors = append(errors, fmt.Sprintf("scripts[%d]: command is required", i))
+		}
+		if source.TimeoutSecs < 0 {
+			errors = append(errors, fmt.Sprintf("scripts[%d]: timeoutSeconds must not be negative", i))
+		}
+		if source.Dir != "" {
+			if info, err := os.Stat(source.Dir); err != nil || !info.IsDir() {
+				errors = append(errors, fmt.Sprintf("scripts[%d]: dir %s is not a directory", i, source.Dir))
+			}
+		}
+	}
+	if len(errors) > 0 {
+		return fmt.Errorf("invalid data source scripts: %s", strings.Join(errors, "; "))
+	}
+	return nil
+}
+
+// ScriptStatus is the outcome of a script's most recent run
+type ScriptStatus struct {
+	Name     string    `json:"name"`
+	LastRun  time.Time `json:"lastRun"`
+	Duration string    `json:"duration"`
+	OK       bool      `json:"ok"`
+	ExitCode int       `json:"exitCode"`
+	Error    string    `json:"error,omitempty"`
+	Stderr   string    `json:"stderr,omitempty"`
+}
+
+var scriptStatusMu sync.Mutex
+var scriptStatuses = map[string]ScriptStatus{}
+
+func recordScriptStatus(status ScriptStatus) {
+	scriptStatusMu.Lock()
+	defer scriptStatusMu.Unlock()
+
+	scriptStatuses[status.Name] = status
+}
+
+// scriptStatusList returns the latest status of every script that has run, sorted by name
+func scriptStatusList() []ScriptStatus {
+	scriptStatusMu.Lock()
+	defer scriptStatusMu.Unlock()
+
+	list := make([]ScriptStatus, 0, len(scriptStatuses))
+	for _, status := range scriptStatuses {
+		list = append(list, status)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// runScript executes source and parses its stdout as a JSON object. The outcome, including
+// stderr and the exit code of failed runs, is recorded for /api/status.
+func runScript(source ScriptSource) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), source.timeout())
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, source.Command, source.Args...)
+	cmd.Dir = source.Dir
+	cmd.Env = os.Environ()
+	for k, v := range source.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	// Don't wait forever on grandchildren that keep the output pipes open
+	cmd.WaitDelay = 2 * time.Second
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	runErr := cmd.Run()
+
+	status := ScriptStatus{
+		Name:     source.displayName(),
+		LastRun:  start,
+		Duration: time.Since(start).Round(time.Millisecond).String(),
+		ExitCode: cmd.ProcessState.ExitCode(),
+		Stderr:   tailString(strings.TrimSpace(stderr.String()), maxScriptStderr),
+	}
+
+	var data map[string]interface{}
+	var err error
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		err = fmt.Errorf("timed out after %v", source.timeout())
+	case errors.As(runErr, &exitErr):
+		err = fmt.Errorf("exited with code %d", exitErr.ExitCode())
+	case runErr != nil:
+		err = runErr
+	default:
+		if jsonErr := json.Unmarshal(stdout.Bytes(), &data); jsonErr != nil {
+			err = fmt.Errorf("stdout is not a JSON object: %w", jsonErr)
+		}
+	}
+
+	if err != nil {
+		status.Error = err.Error()
+		recordScriptStatus(status)
+		return nil, err
+	}
+
+	status.OK = true
+	recordScriptStatus(status)
+	return data, nil
+}
+
+func tailString(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return "..." + s[len(s)-max:]
+}
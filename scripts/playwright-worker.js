@@ -0,0 +1,121 @@
+#!/usr/bin/env node
+
+/**
+ * Long-lived Playwright render worker
+ * Keeps one Chromium instance running and renders HTML to PNG on request.
+ *
+ * Protocol (one JSON object per line):
+ *   stdin:  {"id": 1, "html": "<html>...", "output": "/tmp/x.png", "width": 800, "height": 480}
+ *   stdout: {"id": 1, "ok": true}  or  {"id": 1, "ok": false, "error": "message"}
+ *
+ * The worker exits when stdin is closed. If Chromium crashes it is relaunched
+ * on the next request.
+ */
+
+const playwright = require('playwright');
+const fs = require('fs');
+const path = require('path');
+const readline = require('readline');
+
+let browserPromise = null;
+
+function getBrowser() {
+    if (!browserPromise) {
+        browserPromise = playwright.chromium.launch({
+            headless: true,
+            args: [
+                '--no-sandbox',
+                '--disable-setuid-sandbox',
+                '--disable-dev-shm-usage',
+                '--disable-gpu',
+            ],
+        }).then(browser => {
+            browser.on('disconnected', () => {
+                process.stderr.write('Browser disconnected, will relaunch on next render\n');
+                browserPromise = null;
+            });
+            return browser;
+        }).catch(error => {
+            browserPromise = null;
+            throw error;
+        });
+    }
+    return browserPromise;
+}
+
+async function render(request) {
+    const browser = await getBrowser();
+    const page = await browser.newPage({
+        viewport: {
+            width: parseInt(request.width, 10),
+            height: parseInt(request.height, 10),
+        },
+    });
+
+    try {
+        await page.setContent(request.html, {
+            waitUntil: 'networkidle',
+            timeout: 30000, // 30 second timeout
+        });
+
+        // Wait a bit for any dynamic content or animations
+        await page.waitForTimeout(500);
+
+        const outputDir = path.dirname(request.output);
+        if (!fs.existsSync(outputDir)) {
+            fs.mkdirSync(outputDir, { recursive: true });
+        }
+
+        await page.screenshot({
+            path: request.output,
+            type: 'png',
+            fullPage: false,
+        });
+    } finally {
+        await page.close();
+    }
+}
+
+function respond(response) {
+    process.stdout.write(JSON.stringify(response) + '\n');
+}
+
+const rl = readline.createInterface({ input: process.stdin, terminal: false });
+
+rl.on('line', async line => {
+    if (!line.trim()) {
+        return;
+    }
+
+    let request;
+    try {
+        request = JSON.parse(line);
+    } catch (error) {
+        process.stderr.write(`Invalid request: ${error.message}\n`);
+        return;
+    }
+
+    try {
+        await render(request);
+        respond({ id: request.id, ok: true });
+    } catch (error) {
+        respond({ id: request.id, ok: false, error: error.message });
+    }
+});
+
+rl.on('close', async () => {
+    if (browserPromise) {
+        try {
+            const browser = await browserPromise;
+            await browser.close();
+        } catch (error) {
+            // Browser already gone
+        }
+    }
+    process.exit(0);
+});
+
+// Warm up the browser so the first render doesn't pay the startup cost
+getBrowser().catch(error => {
+    process.stderr.write(`Failed to launch browser: ${error.message}\n`);
+});
@@ -22,7 +22,7 @@ fi
 echo "Building Go binary..."
 # On Linux, tray_noop.go will be included (build tag !windows)
 # On Windows, tray.go will be included (build tag windows)
-go build -o trmnl-renderer ./main.go ./render.go ./server.go ./styles.go ./views.go ./rotation.go ./tray_noop.go
+go build -o trmnl-renderer .
 
 echo "Build complete!"
 echo ""
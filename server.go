@@ -2,27 +2,47 @@ package main
 
 import (
 	"encoding/json"
-	"io"
+	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 )
 
 func setupServer() {
 	// TRMNL /api/setup endpoint
+	// Registers the device by its MAC (ID header) and hands out per-device credentials
 	http.HandleFunc("/api/setup", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		
+		mac := r.Header.Get("ID")
+		if mac == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Missing ID header (device MAC address)"})
+			return
+		}
+		
+		device, created, err := deviceRegistry.Register(mac)
+		if err != nil {
+			log.Printf("Device setup failed for %s: %v", mac, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to register device"})
+			return
+		}
+		if created {
+			log.Printf("Registered new device %s as %s", device.MAC, device.FriendlyID)
+		}
+		
 		baseURL := "http://" + r.Host
-		imageURL := baseURL + "/screen.bmp"
 		
 		response := map[string]string{
-			"api_key":    config.TRMNL.APIKey,
-			"friendly_id": config.TRMNL.FriendlyID,
-			"image_url":  imageURL,
+			"api_key":     device.APIKey,
+			"friendly_id": device.FriendlyID,
+			"image_url":   baseURL + deviceImagePath(device),
 		}
 		
 		w.WriteHeader(http.StatusOK)
@@ -35,34 +55,83 @@ func setupServer() {
 		w.Header().Set("Content-Type", "application/json")
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		
-		token := r.Header.Get("Access-Token")
-		if token != config.TRMNL.APIKey {
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid Access-Token"})
+		// The global key from config.json keeps working for single-device setups
+		device, ok := authenticateDevice(w, r)
+		if !ok {
 			return
 		}
+		imagePath := "/screen.bmp"
+		refreshRate := config.TRMNL.RefreshRateSecs
+		if device != nil {
+			imagePath = deviceImagePath(device)
+			refreshRate = device.refreshRate()
+		}
+		
+		// Record battery, signal and firmware headers sent with every poll
+		key := telemetryKey(device, r)
+		if sample, ok := telemetryFromRequest(r); ok {
+			telemetryStore.Record(key, sample)
+		}
+		
+		// Quiet hours, low battery, alerts and view changes adjust the configured rate
+		refreshRate, reason := refreshRateFor(device, key, refreshRate, time.Now())
+		recordRefreshDecision(key, refreshRate, reason)
 		
 		baseURL := "http://" + r.Host
-		imageURL := baseURL + "/screen.bmp"
+		imageURL := baseURL + imagePath
+		
+		// The filename is the revision of the image the device will get, so the device only
+		// redraws when the content changed
+		filename := "current"
+		imageFile, ok := screenImageFile()
+		if device != nil {
+			imageFile, ok = deviceImageFile(device, filepath.Ext(imagePath), true)
+		}
+		if ok {
+			if revision, err := imageRevisions.Get(imageFile); err == nil {
+				filename = revision
+				imageURL += "?v=" + revision
+			}
+		}
 		
-		// TRMNL device expects this exact structure (stable across refreshes)
+		// TRMNL device expects this exact structure
 		response := map[string]interface{}{
 			"status":        0,
 			"image_url":     imageURL,
-			"filename":      "current",
-			"refresh_rate":  strconv.Itoa(config.TRMNL.RefreshRateSecs),
+			"filename":      filename,
+			"refresh_rate":  strconv.Itoa(refreshRate),
 			"update_firmware": false,
 			"reset_firmware": false,
 		}
 		
+		// Firmware updates and resets are only offered to registered devices
+		if device != nil {
+			current := strings.TrimSpace(r.Header.Get("FW-Version"))
+			if image, ok := firmwareOffer(deviceRegistry.Snapshot(device), current); ok {
+				response["update_firmware"] = true
+				response["firmware_url"] = baseURL + "/firmware/" + url.PathEscape(image.File)
+				log.Printf("Device %s: offering firmware %s (running %s)", device.FriendlyID, image.Version, current)
+			}
+			if deviceRegistry.TakeReset(device) {
+				response["reset_firmware"] = true
+				log.Printf("Device %s: sending reset", device.FriendlyID)
+			}
+		}
+		
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(response)
 	})
 
+	// Diagnostic logs posted by the firmware
+	http.HandleFunc("/api/log", handleDeviceLogUpload)
+
 	// Serve screen.bmp (or screen.png as fallback)
 	http.HandleFunc("/screen.bmp", serveImage)
 	http.HandleFunc("/screen.png", serveImage)
 
+	// Per-device images: /devices/<friendly_id>.bmp (or .png)
+	http.HandleFunc("/devices/", serveDeviceImage)
+
 	// Manual render trigger
 	http.HandleFunc("/api/render", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -88,6 +157,169 @@ func setupServer() {
 		})
 	})
 
+	// Skip to the next view in the rotation (releases a pinned view)
+	http.HandleFunc("/api/rotation/next", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if !requireToken(w, r, "Rotation controls") {
+			return
+		}
+
+		view := skipView()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"view":    view.Name,
+		})
+	})
+
+	// Pin a view: POST {"view": "<name>"} keeps it on screen, DELETE resumes the rotation
+	http.HandleFunc("/api/rotation/pin", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+
+		var req struct {
+			View string `json:"view"`
+		}
+		if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if !requireToken(w, r, "Rotation controls") {
+			return
+		}
+		if r.Method == http.MethodPost {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.View == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "Request body must be {\"view\": \"name\"}"})
+				return
+			}
+		}
+
+		if err := pinView(req.View); err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"pinned":  req.View,
+		})
+	})
+
+	// Alerts: GET lists active alerts, POST raises one and shows it immediately
+	http.HandleFunc("/api/alerts", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(map[string]interface{}{"alerts": alertStore.List()})
+		case http.MethodPost:
+			if !requireToken(w, r, "Alerts") {
+				return
+			}
+			var req AlertRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "Invalid JSON: " + err.Error()})
+				return
+			}
+			alert, err := newAlertFromRequest(req)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			alertStore.Raise(alert)
+			log.Printf("Alert raised via API: %s (priority %d)", alert.Title, alert.Priority)
+			if err := renderCurrentViewToTRMNL(); err != nil {
+				log.Printf("Warning: Failed to show alert: %v", err)
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(alert)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	// DELETE /api/alerts/<id> clears an alert and resumes the rotation
+	http.HandleFunc("/api/alerts/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		
+		if r.Method != http.MethodDelete {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if !requireToken(w, r, "Alerts") {
+			return
+		}
+		
+		id := strings.TrimPrefix(r.URL.Path, "/api/alerts/")
+		if !alertStore.Clear(id) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Unknown alert"})
+			return
+		}
+		log.Printf("Alert cleared via API: %s", id)
+		if err := renderCurrentViewToTRMNL(); err != nil {
+			log.Printf("Warning: Failed to update screen after clearing alert: %v", err)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+	})
+
+	// Per-view previews: /views/<name>.png (or .bmp), ?render=1 re-renders first
+	http.HandleFunc("/views/", serveViewImage)
+	
+	// View list with last render time, size and validation warnings
+	http.HandleFunc("/api/views", handleViewList)
+
+	// Webhook data push: POST replaces and PATCH merges a view's data (bearer token required)
+	http.HandleFunc("/api/views/", handleViewData)
+
+	// Live preview: /preview/<name> re-renders on template/data changes and updates over SSE
+	http.HandleFunc("/preview/", handlePreviewPage)
+	http.HandleFunc("/api/preview/events", handlePreviewEvents)
+
+	// Browser UI: current screen, view previews, devices and rotation controls
+	http.Handle("/admin/", adminHandler())
+
+	// Re-read config.json (same as editing the file, but synchronous)
+	http.HandleFunc("/api/config/reload", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		
+		if err := reloadConfig(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+		
+		configMu.RLock()
+		defer configMu.RUnlock()
+		viewNames := make([]string, 0, len(views))
+		for _, view := range views {
+			viewNames = append(viewNames, view.Name)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"views":   viewNames,
+		})
+	})
+
 	// Status endpoint
 	http.HandleFunc("/api/status", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -108,12 +340,95 @@ func setupServer() {
 				"renderDuration":    renderStats.RenderDuration.String(),
 				"conversionDuration": renderStats.ConversionDuration.String(),
 				"outputSize":        renderStats.OutputSize,
+				"cacheHits":         renderStats.CacheHits,
+				"cacheMisses":       renderStats.CacheMisses,
 			}
 		}
+		hits, misses := renderCache.Stats()
+		response["renderCache"] = map[string]interface{}{
+			"enabled": renderCacheEnabled(),
+			"hits":    hits,
+			"misses":  misses,
+		}
+		state := currentRotation()
+		rotation := map[string]interface{}{
+			"currentView": state.view.Name,
+			"pinnedView":  state.pinned,
+		}
+		if !state.lastRotation.IsZero() {
+			rotation["lastRotation"] = state.lastRotation.Format(time.RFC3339)
+		}
+		response["rotation"] = rotation
+		if !configReloadStatus.LastAttempt.IsZero() {
+			response["configReload"] = configReloadStatus
+		}
+		if scripts := scriptStatusList(); len(scripts) > 0 {
+			response["scripts"] = scripts
+		}
 		
 		json.NewEncoder(w).Encode(response)
 	})
 
+	// Device list with latest telemetry (add ?history=1 for all recorded samples)
+	http.HandleFunc("/api/devices", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		
+		statuses := deviceStatuses()
+		if r.URL.Query().Get("history") == "" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"devices": statuses})
+			return
+		}
+		
+		type deviceWithHistory struct {
+			DeviceStatus
+			History []TelemetrySample `json:"history"`
+		}
+		result := make([]deviceWithHistory, 0, len(statuses))
+		for _, status := range statuses {
+			result = append(result, deviceWithHistory{
+				DeviceStatus: status,
+				History:      telemetryStore.History(status.Key),
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"devices": result})
+	})
+
+	// Device logs and admin actions: /api/devices/<id>/logs, /firmware and /reset
+	http.HandleFunc("/api/devices/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		
+		id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/devices/"), "/")
+		device, ok := deviceRegistry.ByFriendlyID(id)
+		if action == "logs" {
+			// Unregistered devices keep their logs under their telemetry key
+			if ok {
+				id = device.FriendlyID
+			}
+			handleDeviceLogs(w, r, id)
+			return
+		}
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Unknown device"})
+			return
+		}
+		switch action {
+		case "firmware":
+			handleDeviceFirmware(w, r, device)
+		case "reset":
+			handleDeviceReset(w, r, device)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Unknown device action"})
+		}
+	})
+
+	// Firmware store: available images and the files offered to devices
+	http.HandleFunc("/api/firmware", handleFirmwareList)
+	http.HandleFunc("/firmware/", serveFirmware)
+
 	// Root endpoint
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -121,11 +436,25 @@ func setupServer() {
 		response := map[string]interface{}{
 			"service": "TRMNL Renderer",
 			"endpoints": map[string]string{
-				"setup":   baseURL + "/api/setup",
-				"display": baseURL + "/api/display",
-				"image":   baseURL + "/screen.bmp",
-				"render":  baseURL + "/api/render (POST)",
-				"status":  baseURL + "/api/status",
+				"setup":          baseURL + "/api/setup",
+				"display":        baseURL + "/api/display",
+				"image":          baseURL + "/screen.bmp",
+				"render":         baseURL + "/api/render (POST)",
+				"status":         baseURL + "/api/status",
+				"devices":        baseURL + "/api/devices",
+				"reload":         baseURL + "/api/config/reload (POST)",
+				"alerts":         baseURL + "/api/alerts",
+				"views":          baseURL + "/api/views",
+				"preview":        baseURL + "/views/<name>.png",
+				"webhook":        baseURL + "/api/views/<name>/data (POST/PATCH)",
+				"next":           baseURL + "/api/rotation/next (POST)",
+				"pin":            baseURL + "/api/rotation/pin (POST/DELETE)",
+				"admin":          baseURL + "/admin/",
+				"live":           baseURL + "/preview/<name>",
+				"firmware":       baseURL + "/api/firmware",
+				"deviceFirmware": baseURL + "/api/devices/<id>/firmware (GET/PUT)",
+				"deviceReset":    baseURL + "/api/devices/<id>/reset (POST/DELETE)",
+				"deviceLogs":     baseURL + "/api/devices/<id>/logs",
 			},
 		}
 		json.NewEncoder(w).Encode(response)
@@ -134,12 +463,23 @@ func setupServer() {
 
 func serveImage(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	// TRMNL device always fetches image_url on each poll - use no-cache
-	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-	w.Header().Set("Pragma", "no-cache")
-	w.Header().Set("Expires", "0")
 	
 	// Serve screen.bmp from configured output path (TRMNL expects stable URL)
+	path, ok := screenImageFile()
+	if !ok {
+		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Image not yet generated"})
+		return
+	}
+	
+	// The ETag lets the device skip downloading an image it already shows
+	serveImageFile(w, r, path)
+}
+
+// screenImageFile returns the file served at /screen.bmp: the BMP output, or the PNG
+// output as fallback
+func screenImageFile() (string, bool) {
 	bmpPath := config.Render.OutputPath
 	pngPath := ""
 	
@@ -152,33 +492,82 @@ func serveImage(w http.ResponseWriter, r *http.Request) {
 		pngPath = config.Render.OutputPath
 	}
 	
-	var finalPath string
-	var contentType string
-	
 	// Try BMP first (preferred for TRMNL)
 	if _, err := os.Stat(bmpPath); err == nil {
-		finalPath = bmpPath
-		contentType = "image/bmp"
+		return bmpPath, true
 	} else if _, err := os.Stat(pngPath); err == nil {
 		// Fallback to PNG
-		finalPath = pngPath
-		contentType = "image/png"
-	} else {
+		return pngPath, true
+	}
+	return "", false
+}
+
+// deviceImagePath returns the image URL path for a device. Devices without their own
+// playlist share the global /screen.bmp.
+func deviceImagePath(device *Device) string {
+	profile := deviceProfile(device)
+	if len(device.Views) == 0 && profile.Name == defaultProfileName {
+		return "/screen.bmp"
+	}
+	return "/devices/" + device.FriendlyID + profile.imageExt()
+}
+
+func serveDeviceImage(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/devices/")
+	ext := filepath.Ext(name)
+	if ext != ".bmp" && ext != ".png" {
+		http.NotFound(w, r)
+		return
+	}
+	
+	device, ok := deviceRegistry.ByFriendlyID(strings.TrimSuffix(name, ext))
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Image not yet generated"})
+		json.NewEncoder(w).Encode(map[string]string{"error": "Unknown device"})
 		return
 	}
 	
-	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Access-Control-Allow-Origin", "*")
 	
-	file, err := os.Open(finalPath)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to open image"})
+	path, ok := deviceImageFile(device, ext, false)
+	if !ok {
+		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Image not yet generated"})
 		return
 	}
-	defer file.Close()
-	
-	io.Copy(w, file)
+	serveImageFile(w, r, path)
 }
 
+// deviceImageFile returns the file served at /devices/<friendly_id><ext>: the alert screen
+// while an alert is active, otherwise the image of the device's current view in its output
+// profile. Devices without a playlist or profile get the shared screen image. The poll
+// advances the device's playlist; the image fetch serves the view the poll announced.
+func deviceImageFile(device *Device, ext string, poll bool) (string, bool) {
+	profile := deviceProfile(device)
+	showView := deviceRegistry.ShownView
+	if poll {
+		showView = deviceRegistry.CurrentView
+	}
+	view, ok := showView(device)
+	if !ok {
+		if profile.Name == defaultProfileName {
+			return screenImageFile()
+		}
+		// The global rotation, rendered for the device's profile
+		view = currentRotation().view
+	}
+
+	// Alerts preempt the device playlist too
+	if path, ok := alertImageFile(profile, ext); ok {
+		return path, true
+	}
+
+	path := filepath.Join(config.Paths.OutputDir, profile.imageName(view.Name)+ext)
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
@@ -1,25 +1,48 @@
 package main
 
+import "fmt"
+
+// baseStyles returns the base styles for a width x height screen: the default screen, an
+// output profile or a mashup region
+func baseStyles(width, height int) string {
+	return sizeStyles(width, height) + tailwindCSS
+}
+
+// sizeStyles returns the enforced dimensions of the page and its content area
+func sizeStyles(width, height int) string {
+	return fmt.Sprintf(`
+    /* ENFORCED: Root and body dimensions - DO NOT OVERRIDE */
+    html, body {
+      width: %[1]dpx !important;
+      height: %[2]dpx !important;
+      max-width: %[1]dpx !important;
+      max-height: %[2]dpx !important;
+    }
+    
+    /* ENFORCED: Content area height - screen height - 50 header - 10 padding */
+    .content {
+      max-height: %[3]dpx !important;
+    }
+    
+    /* Prevent any element from exceeding bounds */
+    * {
+      max-width: %[1]dpx;
+    }
+`, width, height, height-60)
+}
+
+// tailwindCSS holds the base styles that don't depend on the screen size (see baseStyles)
 const tailwindCSS = `    * {
       margin: 0;
       padding: 0;
       box-sizing: border-box;
     }
     
-    /* ENFORCED: Root and body dimensions - DO NOT OVERRIDE */
     html {
-      width: 800px !important;
-      height: 480px !important;
-      max-width: 800px !important;
-      max-height: 480px !important;
       overflow: hidden !important;
     }
     
     body {
-      width: 800px !important;
-      height: 480px !important;
-      max-width: 800px !important;
-      max-height: 480px !important;
       background: #ffffff;
       color: #000000;
       font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Arial, sans-serif;
@@ -72,12 +95,6 @@ const tailwindCSS = `    * {
       padding: 10px;
       min-height: 0 !important;
       overflow: hidden !important;
-      max-height: 420px !important; /* 480 - 50 header - 10 padding */
-    }
-    
-    /* Prevent any element from exceeding bounds */
-    * {
-      max-width: 800px;
     }
     
     .dashboard-grid {
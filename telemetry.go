@@ -0,0 +1,221 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TelemetrySample is one set of headers reported by a device on an /api/display poll
+type TelemetrySample struct {
+	Time            time.Time         `json:"time"`
+	BatteryVoltage  float64           `json:"batteryVoltage,omitempty"`
+	RSSI            int               `json:"rssi,omitempty"`
+	FirmwareVersion string            `json:"firmwareVersion,omitempty"`
+	RefreshRate     int               `json:"refreshRate,omitempty"`
+	Width           int               `json:"width,omitempty"`
+	Height          int               `json:"height,omitempty"`
+	Extra           map[string]string `json:"extra,omitempty"` // Other known headers (Model, Special-Function, ...)
+}
+
+// DeviceStatus is the per-device summary exposed via /api/devices and to templates
+type DeviceStatus struct {
+	Key        string           `json:"key"`
+	FriendlyID string           `json:"friendlyId,omitempty"`
+	Name       string           `json:"name,omitempty"`
+	MAC        string           `json:"mac,omitempty"`
+	Profile    string           `json:"profile,omitempty"` // Output profile from render.profiles
+	LastSeen   time.Time        `json:"lastSeen"`
+	Telemetry  *TelemetrySample `json:"telemetry,omitempty"`
+	Refresh    *RefreshDecision `json:"refresh,omitempty"` // refresh_rate last sent to the device
+}
+
+// TelemetryStore keeps a bounded history of samples per device
+type TelemetryStore struct {
+	mu         sync.Mutex
+	maxSamples int
+	history    map[string][]TelemetrySample
+}
+
+// telemetryHistorySize keeps roughly one day of samples at the default 5 minute refresh rate
+const telemetryHistorySize = 288
+
+var telemetryStore = newTelemetryStore(telemetryHistorySize)
+
+// extraTelemetryHeaders are recorded verbatim when present
+var extraTelemetryHeaders = []string{"Model", "Special-Function", "Percent-Charged"}
+
+func newTelemetryStore(maxSamples int) *TelemetryStore {
+	return &TelemetryStore{
+		maxSamples: maxSamples,
+		history:    make(map[string][]TelemetrySample),
+	}
+}
+
+// telemetryFromRequest parses device telemetry headers. ok is false if the request carries none.
+func telemetryFromRequest(r *http.Request) (TelemetrySample, bool) {
+	sample := TelemetrySample{Time: time.Now()}
+	found := false
+
+	if v := strings.TrimSpace(r.Header.Get("Battery-Voltage")); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			sample.BatteryVoltage = f
+			found = true
+		}
+	}
+	if v, ok := headerInt(r, "RSSI"); ok {
+		sample.RSSI = v
+		found = true
+	}
+	if v := strings.TrimSpace(r.Header.Get("FW-Version")); v != "" {
+		sample.FirmwareVersion = v
+		found = true
+	}
+	if v, ok := headerInt(r, "Refresh-Rate"); ok {
+		sample.RefreshRate = v
+		found = true
+	}
+	if v, ok := headerInt(r, "Width"); ok {
+		sample.Width = v
+		found = true
+	}
+	if v, ok := headerInt(r, "Height"); ok {
+		sample.Height = v
+		found = true
+	}
+
+	for _, name := range extraTelemetryHeaders {
+		if v := strings.TrimSpace(r.Header.Get(name)); v != "" {
+			if sample.Extra == nil {
+				sample.Extra = make(map[string]string)
+			}
+			sample.Extra[name] = v
+			found = true
+		}
+	}
+
+	return sample, found
+}
+
+func headerInt(r *http.Request, name string) (int, bool) {
+	v := strings.TrimSpace(r.Header.Get(name))
+	if v == "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// Record appends a sample for the device, dropping the oldest beyond maxSamples
+func (s *TelemetryStore) Record(key string, sample TelemetrySample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	samples := append(s.history[key], sample)
+	if len(samples) > s.maxSamples {
+		samples = samples[len(samples)-s.maxSamples:]
+	}
+	s.history[key] = samples
+}
+
+// Latest returns the most recent sample for the device
+func (s *TelemetryStore) Latest(key string) (TelemetrySample, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	samples := s.history[key]
+	if len(samples) == 0 {
+		return TelemetrySample{}, false
+	}
+	return samples[len(samples)-1], true
+}
+
+// History returns a copy of all samples for the device, oldest first
+func (s *TelemetryStore) History(key string) []TelemetrySample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]TelemetrySample(nil), s.history[key]...)
+}
+
+// Keys returns all devices that have reported telemetry
+func (s *TelemetryStore) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.history))
+	for key := range s.history {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// telemetryKey identifies the device for telemetry: the registered friendly ID, the
+// MAC from the ID header for the shared key, or "default" when neither is known
+func telemetryKey(device *Device, r *http.Request) string {
+	if device != nil {
+		return device.FriendlyID
+	}
+	if mac := normalizeMAC(r.Header.Get("ID")); mac != "" {
+		return mac
+	}
+	return "default"
+}
+
+// deviceStatuses combines registered devices with their latest telemetry. Devices that
+// report telemetry without being registered (shared API key) are included by key.
+func deviceStatuses() []DeviceStatus {
+	statuses := []DeviceStatus{}
+	seen := make(map[string]bool)
+
+	registered := []Device{}
+	if deviceRegistry != nil {
+		registered = deviceRegistry.List()
+	}
+
+	for _, device := range registered {
+		status := DeviceStatus{
+			Key:        device.FriendlyID,
+			FriendlyID: device.FriendlyID,
+			Name:       device.Name,
+			MAC:        device.MAC,
+			Profile:    device.Profile,
+		}
+		if sample, ok := telemetryStore.Latest(device.FriendlyID); ok {
+			status.LastSeen = sample.Time
+			status.Telemetry = &sample
+		}
+		if decision, ok := lastRefreshDecision(device.FriendlyID); ok {
+			status.Refresh = &decision
+		}
+		seen[device.FriendlyID] = true
+		statuses = append(statuses, status)
+	}
+
+	for _, key := range telemetryStore.Keys() {
+		if seen[key] {
+			continue
+		}
+		status := DeviceStatus{Key: key}
+		if key != "default" {
+			status.MAC = key
+		}
+		if sample, ok := telemetryStore.Latest(key); ok {
+			status.LastSeen = sample.Time
+			status.Telemetry = &sample
+		}
+		if decision, ok := lastRefreshDecision(key); ok {
+			status.Refresh = &decision
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
@@ -25,7 +25,9 @@ func TestDashboard() {
 	}
 
 	// Initialize views
-	initViews()
+	if err := initViews(); err != nil {
+		log.Fatalf("Failed to load views: %v", err)
+	}
 
 	// Find dashboard view
 	var dashboardView View
@@ -56,7 +58,7 @@ func TestDashboard() {
 	fmt.Printf("HTML rendered successfully (%d bytes)\n", len(html))
 
 	// Render to image
-	if err := renderToImage(html, "test-output.png"); err != nil {
+	if err := renderToImage(html, "test-output.png", dashboardView.Dither); err != nil {
 		log.Fatalf("Failed to render image: %v", err)
 	}
 
@@ -110,7 +112,7 @@ func renderViewToTest(viewName string) {
 	fmt.Printf("HTML rendered successfully (%d bytes)\n", len(html))
 
 	// Render to image
-	if err := renderToImage(html, outputFile); err != nil {
+	if err := renderToImage(html, outputFile, targetView.Dither); err != nil {
 		log.Fatalf("Failed to render image: %v", err)
 	}
 
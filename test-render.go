@@ -1,3 +1,6 @@
+//go:build ignore
+
+// Standalone Playwright check, run with: go run test-render.go
 package main
 
 import (
@@ -5,7 +5,6 @@ package main
 
 import (
 	"log"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -36,7 +35,7 @@ func onReady(baseURL string) {
 	mViewImage := systray.AddMenuItem("View Rendered Image", "Open current screen.bmp in browser")
 	
 	// Open server in browser
-	mOpenBrowser := systray.AddMenuItem("Open Server", "Open server dashboard in browser")
+	mOpenBrowser := systray.AddMenuItem("Open Admin", "Open the admin page in browser")
 
 	systray.AddSeparator()
 
@@ -55,7 +54,7 @@ func onReady(baseURL string) {
 				imageURL := baseURL + "/screen.bmp"
 				openBrowser(imageURL)
 			case <-mOpenBrowser.ClickedCh:
-				openBrowser(baseURL)
+				openBrowser(baseURL + "/admin/")
 			case <-mEditConfig.ClickedCh:
 				editConfig()
 			case <-mQuit.ClickedCh:
@@ -3,8 +3,24 @@
 
 package main
 
+import (
+	"log"
+	"os/exec"
+	"runtime"
+)
+
 // Stub implementation for non-Windows systems
 func startSystemTray(baseURL string) {
 	// No-op on non-Windows
 }
 
+func openBrowser(url string) {
+	command := "xdg-open"
+	if runtime.GOOS == "darwin" {
+		command = "open"
+	}
+	if err := exec.Command(command, url).Start(); err != nil {
+		log.Printf("Failed to open browser: %v", err)
+	}
+}
+
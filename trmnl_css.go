@@ -0,0 +1,358 @@
+package main
+
+// trmnlFrameworkCSS implements the layout primitives and utility classes of TRMNL's design
+// system (view, layout, columns, item, title_bar, value--xlarge, label, ...) for 800x480 1-bit
+// output, so markup written for TRMNL plugins renders here. Include it after the base styles
+// with {{.TRMNLStyles}} (Liquid: {{ trmnl_styles }}). Views are sized relative to the body, so
+// the sheet also works in mashup regions. Grays are left to the dither step.
+const trmnlFrameworkCSS = `    /* TRMNL framework: screen and views */
+    .screen {
+      width: 100%;
+      height: 100%;
+      background: #ffffff;
+      color: #000000;
+      display: flex;
+      flex-direction: column;
+      overflow: hidden;
+    }
+
+    .view {
+      display: flex;
+      flex-direction: column;
+      overflow: hidden;
+      background: #ffffff;
+    }
+
+    .view--full { width: 100%; height: 100%; }
+    .view--half_horizontal { width: 100%; height: 50%; }
+    .view--half_vertical { width: 50%; height: 100%; }
+    .view--quadrant { width: 50%; height: 50%; }
+
+    /* Mashups: several views on one screen */
+    .mashup {
+      width: 100%;
+      height: 100%;
+      display: flex;
+      flex-wrap: wrap;
+      overflow: hidden;
+    }
+
+    /* Views fill rows in order; layouts with a full-height view fill columns instead */
+    .mashup--1Lx2R, .mashup--2Lx1R { flex-direction: column; }
+    .mashup .view { border: 1px solid #000000; }
+
+    /* Layout: the main content area of a view */
+    .layout {
+      flex: 1;
+      display: flex;
+      flex-direction: row;
+      align-items: center;
+      justify-content: center;
+      gap: 16px;
+      padding: 16px 20px;
+      min-height: 0;
+      overflow: hidden;
+    }
+
+    .layout--row { flex-direction: row; }
+    .layout--col { flex-direction: column; }
+    .layout--left { justify-content: flex-start; }
+    .layout--right { justify-content: flex-end; }
+    .layout--top { align-items: flex-start; }
+    .layout--bottom { align-items: flex-end; }
+    .layout--center { align-items: center; justify-content: center; }
+    .layout--center-x { justify-content: center; }
+    .layout--center-y { align-items: center; }
+    .layout--stretch { align-items: stretch; }
+    .layout--col.layout--left { justify-content: center; align-items: flex-start; }
+    .layout--col.layout--right { justify-content: center; align-items: flex-end; }
+    .layout--col.layout--top { align-items: center; justify-content: flex-start; }
+    .layout--col.layout--bottom { align-items: center; justify-content: flex-end; }
+    .layout--col.layout--stretch { align-items: stretch; }
+
+    /* Columns, grid and flex helpers */
+    .columns {
+      display: flex;
+      flex-direction: row;
+      gap: 20px;
+      width: 100%;
+      height: 100%;
+      min-height: 0;
+    }
+
+    .column {
+      flex: 1;
+      display: flex;
+      flex-direction: column;
+      gap: 10px;
+      min-width: 0;
+      min-height: 0;
+      overflow: hidden;
+    }
+
+    .grid {
+      display: grid;
+      grid-template-columns: repeat(2, 1fr);
+      gap: 16px;
+      width: 100%;
+    }
+
+    .grid--cols-1 { grid-template-columns: 1fr; }
+    .grid--cols-2 { grid-template-columns: repeat(2, 1fr); }
+    .grid--cols-3 { grid-template-columns: repeat(3, 1fr); }
+    .grid--cols-4 { grid-template-columns: repeat(4, 1fr); }
+    .grid--cols-5 { grid-template-columns: repeat(5, 1fr); }
+    .grid--cols-6 { grid-template-columns: repeat(6, 1fr); }
+    .col--span-2 { grid-column: span 2; }
+    .col--span-3 { grid-column: span 3; }
+    .col--span-4 { grid-column: span 4; }
+
+    .row {
+      display: flex;
+      flex-direction: row;
+      align-items: center;
+      gap: 10px;
+    }
+
+    .flex { display: flex; gap: 10px; }
+    .flex--row { flex-direction: row; }
+    .flex--col { flex-direction: column; }
+    .flex--wrap { flex-wrap: wrap; }
+    .flex--left { justify-content: flex-start; }
+    .flex--right { justify-content: flex-end; }
+    .flex--center-x { justify-content: center; }
+    .flex--center-y { align-items: center; }
+    .flex--center { align-items: center; justify-content: center; }
+    .flex--top { align-items: flex-start; }
+    .flex--bottom { align-items: flex-end; }
+    .flex--between { justify-content: space-between; }
+    .flex--around { justify-content: space-around; }
+    .flex--evenly { justify-content: space-evenly; }
+    .flex--stretch { align-items: stretch; }
+    .stretch, .stretch-x { flex: 1; min-width: 0; }
+    .stretch-y { align-self: stretch; }
+    .w--full { width: 100%; }
+    .h--full { height: 100%; }
+    .hidden { display: none; }
+
+    .gap--none { gap: 0; }
+    .gap--xsmall { gap: 4px; }
+    .gap--small { gap: 8px; }
+    .gap--medium { gap: 16px; }
+    .gap--large { gap: 24px; }
+    .gap--xlarge { gap: 32px; }
+
+    /* Items: a list entry with an optional meta bar, index and content */
+    .item {
+      display: flex;
+      flex-direction: row;
+      align-items: stretch;
+      gap: 10px;
+      min-width: 0;
+    }
+
+    .item .meta {
+      width: 10px;
+      flex-shrink: 0;
+      background: #000000;
+    }
+
+    .item .index {
+      flex-shrink: 0;
+      font-size: 16px;
+      font-weight: bold;
+      line-height: 1.2;
+    }
+
+    .item .icon {
+      flex-shrink: 0;
+      width: 32px;
+      height: 32px;
+    }
+
+    /* Undo the base .content grid inside framework markup */
+    .item .content, .layout .content, .richtext .content {
+      display: flex;
+      flex-direction: column;
+      justify-content: center;
+      gap: 2px;
+      flex: 1;
+      padding: 0;
+      min-width: 0;
+      max-height: none !important;
+    }
+
+    /* Title bar at the bottom of a view */
+    .title_bar {
+      height: 40px;
+      flex-shrink: 0;
+      display: flex;
+      flex-direction: row;
+      align-items: center;
+      gap: 10px;
+      padding: 0 12px;
+      background: #000000;
+      color: #ffffff;
+      overflow: hidden;
+    }
+
+    .title_bar .image {
+      height: 24px;
+      width: 24px;
+    }
+
+    .title_bar .title {
+      font-size: 16px;
+      font-weight: bold;
+      white-space: nowrap;
+    }
+
+    .title_bar .instance {
+      font-size: 14px;
+      white-space: nowrap;
+      overflow: hidden;
+      text-overflow: ellipsis;
+    }
+
+    /* Typography */
+    .title {
+      font-size: 26px;
+      font-weight: bold;
+      line-height: 1.2;
+    }
+
+    .title--small { font-size: 20px; }
+    .title--large { font-size: 32px; }
+    .title--xlarge { font-size: 40px; }
+
+    .value {
+      font-size: 38px;
+      font-weight: bold;
+      line-height: 1;
+      white-space: nowrap;
+    }
+
+    .value--xxxlarge { font-size: 128px; }
+    .value--xxlarge { font-size: 96px; }
+    .value--xlarge { font-size: 72px; }
+    .value--large { font-size: 56px; }
+    .value--medium { font-size: 38px; }
+    .value--small { font-size: 26px; }
+    .value--xsmall { font-size: 20px; }
+    .value--xxsmall { font-size: 16px; }
+    .value--tnums { font-variant-numeric: tabular-nums; }
+
+    .label {
+      font-size: 16px;
+      font-weight: 600;
+      line-height: 1.2;
+    }
+
+    .label--small { font-size: 13px; }
+    .label--large { font-size: 20px; }
+    .label--gray, .label--gray-out { color: #555555; }
+    .label--underline { text-decoration: underline; }
+
+    .label--inverted {
+      background: #000000;
+      color: #ffffff;
+      padding: 2px 6px;
+    }
+
+    .label--outline {
+      border: 1px solid #000000;
+      padding: 1px 5px;
+    }
+
+    /* Badges hug their text instead of stretching across a column */
+    .label--inverted, .label--outline { align-self: flex-start; }
+
+    .description {
+      font-size: 16px;
+      line-height: 1.3;
+    }
+
+    .text--left { text-align: left; }
+    .text--center { text-align: center; }
+    .text--right { text-align: right; }
+    .text--justify { text-align: justify; }
+    .text--black { color: #000000; }
+    .text--white { color: #ffffff; }
+    .text--gray-50 { color: #808080; }
+
+    .clamp--1 {
+      overflow: hidden;
+      white-space: nowrap;
+      text-overflow: ellipsis;
+    }
+
+    .clamp--2, .clamp--3 {
+      overflow: hidden;
+      display: -webkit-box;
+      -webkit-box-orient: vertical;
+    }
+
+    .clamp--2 { -webkit-line-clamp: 2; }
+    .clamp--3 { -webkit-line-clamp: 3; }
+
+    /* Backgrounds (grays are dithered to 1-bit) */
+    .bg--white { background: #ffffff; }
+    .bg--black { background: #000000; color: #ffffff; }
+    .bg--gray-10 { background: #1a1a1a; color: #ffffff; }
+    .bg--gray-25 { background: #404040; color: #ffffff; }
+    .bg--gray-50 { background: #808080; }
+    .bg--gray-75 { background: #bfbfbf; }
+
+    /* Rich text, dividers, images and tables */
+    .richtext {
+      display: flex;
+      flex-direction: column;
+      gap: 10px;
+      font-size: 20px;
+      line-height: 1.4;
+    }
+
+    .content--small { font-size: 16px; }
+    .content--large { font-size: 26px; }
+    .content--center { text-align: center; align-items: center; }
+
+    .divider {
+      border-top: 1px solid #000000;
+      width: 100%;
+      height: 0;
+    }
+
+    .divider--vertical {
+      border-top: none;
+      border-left: 1px solid #000000;
+      width: 0;
+      height: 100%;
+    }
+
+    .image { max-width: 100%; max-height: 100%; }
+    .image--fill { width: 100%; height: 100%; object-fit: fill; }
+    .image--contain { width: 100%; height: 100%; object-fit: contain; }
+    .image--cover { width: 100%; height: 100%; object-fit: cover; }
+
+    .table {
+      width: 100%;
+      border-collapse: collapse;
+      font-size: 16px;
+    }
+
+    .table th {
+      text-align: left;
+      font-weight: bold;
+      border-bottom: 2px solid #000000;
+      padding: 4px 6px;
+    }
+
+    .table td {
+      border-bottom: 1px solid #000000;
+      padding: 4px 6px;
+    }
+
+    .table--condensed th, .table--condensed td {
+      padding: 2px 4px;
+      font-size: 14px;
+    }`
@@ -2,12 +2,12 @@ package main
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"html/template"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -15,16 +15,60 @@ import (
 type View struct {
 	Name     string
 	Template string
+	Engine   string // Template engine: go or liquid
 	DataPath string
+	Sources  []DataSource           // Additional data sources merged after DataPath
+	Duration time.Duration          // How long the view stays on screen (0 = rotationInterval)
+	Schedule []scheduleRule         // When the view may be shown (empty = whenever no scheduled view is active)
+	Alerts   []AlertRule            // Data conditions that raise an alert
+	Options  map[string]interface{} // Free-form per-view options, exposed to templates
+	Dither   DitherSettings         // Per-view overrides of the render dither settings
+	Mashup   *Mashup                // Set for mashup views, which compose other views instead of a template
+}
+
+// ViewConfig is a single entry of the "views" section in config.json
+type ViewConfig struct {
+	Name                string                 `json:"name"`
+	Type                string                 `json:"type,omitempty"`   // "mashup" composes other views; empty for a template view
+	Layout              string                 `json:"layout,omitempty"` // mashup: region layout, e.g. "1Lx1R" or "2x2"
+	Views               []string               `json:"views,omitempty"`  // mashup: views placed in the layout's regions, in order
+	Scale               float64                `json:"scale,omitempty"`  // mashup: render children at region size / scale (default: 1)
+	Template            string                 `json:"template"`
+	Engine              string                 `json:"engine,omitempty"` // go (default) or liquid
+	DataPath            string                 `json:"dataPath"`
+	Sources             []DataSource           `json:"sources,omitempty"`
+	DisplayDurationMins int                    `json:"displayDurationMinutes"`
+	Schedule            []ScheduleRule         `json:"schedule,omitempty"`
+	Alerts              []AlertRule            `json:"alerts,omitempty"`
+	Enabled             *bool                  `json:"enabled,omitempty"`
+	Options             map[string]interface{} `json:"options,omitempty"`
+	DitherSettings                             // dither, threshold, gamma, contrast overrides
 }
 
 type ViewData struct {
-	Title     string
-	Timestamp string
-	Styles    template.CSS
-	Tasks     []Task                   `json:"tasks,omitempty"`
-	Cards     []Card                   `json:"cards,omitempty"`
-	Fields    map[string]interface{}   `json:"fields,omitempty"` // Flexible fields for templating
+	Title       string
+	Timestamp   string
+	Styles      template.CSS
+	TRMNLStyles template.CSS           // TRMNL framework classes (view, layout, item, title_bar, value, label, ...)
+	Tasks       []Task                 `json:"tasks,omitempty"`
+	Cards       []Card                 `json:"cards,omitempty"`
+	Fields      map[string]interface{} `json:"fields,omitempty"`  // Flexible fields for templating
+	Options     map[string]interface{} `json:"options,omitempty"` // Per-view options from config.json
+	Devices     []DeviceStatus         `json:"devices,omitempty"` // Registered devices with latest telemetry
+	Data        map[string]interface{} `json:"data,omitempty"`    // Merged global data sources (jsonFiles, apiEndpoints, scripts)
+	Alert       *Alert                 `json:"alert,omitempty"`   // Set when the view is rendered as an alert screen
+	Width       int                    `json:"width"`             // Render size: the profile's layout size, or a mashup region
+	Height      int                    `json:"height"`            // For the viewport meta tag: width={{.Width}}, height={{.Height}}
+
+	raw      map[string]interface{} // Merged view data, used to evaluate alert rules
+	volatile []string               // Values that change on every render (the default timestamp), left out of the render cache key
+}
+
+// setSize sets the render size and the base styles for it (the default screen, an output
+// profile or a mashup region)
+func (d *ViewData) setSize(width, height int) {
+	d.Width, d.Height = width, height
+	d.Styles = template.CSS(baseStyles(width, height))
 }
 
 type Task struct {
@@ -38,8 +82,12 @@ var currentViewIndex int
 var lastRotationTime time.Time
 var rotationInterval = 15 * time.Minute
 
-func initViews() {
-	views = []View{
+// viewNamePattern restricts view names to characters that are safe in output file names
+var viewNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// defaultViews is used when config.json has no "views" section
+func defaultViews() []View {
+	return []View{
 		{
 			Name:     "todo",
 			Template: "./templates/todo.html",
@@ -55,27 +103,183 @@ func initViews() {
 			Template: "./templates/chores.html",
 			DataPath: "./data/chores.json",
 		},
-		// Add more views here as we create them
 	}
 }
 
-func loadViewData(view View) (*ViewData, error) {
-	data, err := os.ReadFile(view.DataPath)
+// initViews loads the view registry from config.json
+func initViews() error {
+	loaded, err := viewsFromConfig(config)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	views = loaded
+
+	if currentViewIndex >= len(views) {
+		currentViewIndex = 0
+	}
+	return nil
+}
+
+// viewsFromConfig returns the views defined by cfg (the built-in views if it has none)
+func viewsFromConfig(cfg Config) ([]View, error) {
+	if cfg.Views == nil {
+		return defaultViews(), nil
 	}
+	return buildViews(cfg.Views)
+}
+
+// buildViews validates view entries from config.json and converts the enabled ones to Views
+func buildViews(entries []ViewConfig) ([]View, error) {
+	errors := []string{}
+	seen := make(map[string]bool)
+	result := []View{}
+	byName := make(map[string]View) // Template views, including disabled ones, for mashups
 
-	var rawData map[string]interface{}
-	if err := json.Unmarshal(data, &rawData); err != nil {
+	for i, entry := range entries {
+		label := fmt.Sprintf("views[%d]", i)
+		if entry.Name != "" {
+			label = fmt.Sprintf("views[%d] (%s)", i, entry.Name)
+		}
+
+		switch {
+		case entry.Name == "":
+			errors = append(errors, label+": name is required")
+		case !viewNamePattern.MatchString(entry.Name):
+			errors = append(errors, label+": name may only contain letters, digits, '-' and '_'")
+		case entry.Name == "screen":
+			errors = append(errors, label+": name 'screen' is reserved for the TRMNL output image")
+		case entry.Name == alertImageName:
+			errors = append(errors, label+": name 'alert' is reserved for the alert screen")
+		case seen[entry.Name]:
+			errors = append(errors, label+": duplicate view name")
+		}
+		seen[entry.Name] = true
+
+		switch entry.Type {
+		case "":
+			if entry.Template == "" {
+				errors = append(errors, label+": template is required")
+			}
+			if err := validateEngine(entry.Engine); err != nil {
+				errors = append(errors, label+": "+err.Error())
+			}
+			if entry.DataPath == "" && len(entry.Sources) == 0 {
+				errors = append(errors, label+": dataPath or sources is required")
+			}
+		case viewTypeMashup:
+			if err := validateMashup(entry); err != nil {
+				errors = append(errors, label+": "+err.Error())
+			}
+		default:
+			errors = append(errors, fmt.Sprintf("%s: unknown type '%s' (expected mashup or none)", label, entry.Type))
+		}
+		for j, source := range entry.Sources {
+			if err := validateDataSource(source); err != nil {
+				errors = append(errors, fmt.Sprintf("%s: sources[%d]: %v", label, j, err))
+			}
+		}
+		if entry.DisplayDurationMins < 0 {
+			errors = append(errors, label+": displayDurationMinutes must not be negative")
+		}
+		if err := validateDitherSettings(entry.DitherSettings); err != nil {
+			errors = append(errors, label+": "+err.Error())
+		}
+		schedule, err := compileSchedule(entry.Schedule)
+		if err != nil {
+			errors = append(errors, label+": "+err.Error())
+		}
+
+		view := View{
+			Name:     entry.Name,
+			Template: entry.Template,
+			Engine:   viewEngine(entry),
+			DataPath: entry.DataPath,
+			Sources:  entry.Sources,
+			Duration: time.Duration(entry.DisplayDurationMins) * time.Minute,
+			Schedule: schedule,
+			Alerts:   entry.Alerts,
+			Options:  entry.Options,
+			Dither:   entry.DitherSettings,
+		}
+		if entry.Type == viewTypeMashup {
+			view.Template, view.Engine = "", ""
+			view.Mashup = &Mashup{Layout: entry.Layout, Scale: entry.Scale}
+		} else {
+			byName[entry.Name] = view
+		}
+
+		if entry.Enabled != nil && !*entry.Enabled {
+			continue
+		}
+		result = append(result, view)
+	}
+
+	// Mashups may use any template view, including disabled ones that don't rotate on their own
+	for i, entry := range entries {
+		if entry.Type != viewTypeMashup {
+			continue
+		}
+		children := []View{}
+		for _, name := range entry.Views {
+			child, ok := byName[name]
+			if !ok {
+				errors = append(errors, fmt.Sprintf("views[%d] (%s): unknown view '%s' (mashups can only contain template views)", i, entry.Name, name))
+				continue
+			}
+			children = append(children, child)
+		}
+		for _, view := range result {
+			if view.Name == entry.Name && view.Mashup != nil {
+				view.Mashup.Children = children
+			}
+		}
+	}
+
+	// Alert rules can show any view findView resolves: enabled views and mashup children
+	shown := make(map[string]bool)
+	for _, view := range result {
+		shown[view.Name] = true
+		if view.Mashup != nil {
+			for _, child := range view.Mashup.Children {
+				shown[child.Name] = true
+			}
+		}
+	}
+	for i, entry := range entries {
+		if err := validateAlertRules(entry.Alerts, shown); err != nil {
+			label := fmt.Sprintf("views[%d]", i)
+			if entry.Name != "" {
+				label = fmt.Sprintf("views[%d] (%s)", i, entry.Name)
+			}
+			errors = append(errors, label+": "+err.Error())
+		}
+	}
+
+	if len(errors) > 0 {
+		return nil, fmt.Errorf("invalid views configuration: %s", strings.Join(errors, "; "))
+	}
+
+	return result, nil
+}
+
+func loadViewData(view View) (*ViewData, error) {
+	rawData, err := loadViewSources(view)
+	if err != nil {
 		return nil, err
 	}
 
 	viewData := &ViewData{
-		Title:     "TRMNL Dashboard",
-		Timestamp: time.Now().Format("2006-01-02 15:04:05"),
-		Styles:    template.CSS(tailwindCSS),
-		Fields:    make(map[string]interface{}),
-	}
+		Title:       "TRMNL Dashboard",
+		Timestamp:   time.Now().Format("2006-01-02 15:04:05"),
+		TRMNLStyles: template.CSS(trmnlFrameworkCSS),
+		Fields:      make(map[string]interface{}),
+		Options:     view.Options,
+		Devices:     deviceStatuses(),
+		Data:        currentSourceData(),
+		raw:         rawData,
+	}
+	// Sized for the default profile; renders for other profiles and mashup regions resize it
+	viewData.setSize(defaultProfile().layoutSize())
 
 	// Extract title and timestamp (these are special fields)
 	if title, ok := rawData["title"].(string); ok {
@@ -83,6 +287,8 @@ func loadViewData(view View) (*ViewData, error) {
 	}
 	if ts, ok := rawData["timestamp"].(string); ok {
 		viewData.Timestamp = ts
+	} else {
+		viewData.volatile = append(viewData.volatile, viewData.Timestamp)
 	}
 
 	// Extract tasks for todo view
@@ -142,15 +348,15 @@ func loadViewData(view View) (*ViewData, error) {
 }
 
 // validateTemplate checks if a rendered template follows required structure and constraints
-func validateTemplate(html string, viewName string) error {
+func validateTemplate(html string, viewName string, width, height int) error {
 	errors := []string{}
 	
-	// Check for required viewport meta tag
-	viewportPattern := fmt.Sprintf(`width=%d, height=%d`, config.Render.Width, config.Render.Height)
-	if !strings.Contains(html, viewportPattern) && 
-	   !strings.Contains(html, "width=800, height=480") {
+	// Check for required viewport meta tag matching the render size (the profile's layout
+	// size or the mashup region); templates get it as {{.Width}} and {{.Height}}
+	viewportPattern := fmt.Sprintf(`width=%d, height=%d`, width, height)
+	if !strings.Contains(html, viewportPattern) {
 		errors = append(errors, fmt.Sprintf("template must include viewport meta tag: width=%d, height=%d", 
-			config.Render.Width, config.Render.Height))
+			width, height))
 	}
 	
 	// Check for {{.Styles}} injection point (should be in rendered output as actual styles)
@@ -167,10 +373,10 @@ func validateTemplate(html string, viewName string) error {
 	// Check for dangerous body width/height overrides that might break layout
 	if strings.Contains(html, "body {") {
 		bodyStyles := extractBodyStyles(html)
-		if strings.Contains(bodyStyles, "width:") && !strings.Contains(bodyStyles, "800px") {
+		if strings.Contains(bodyStyles, "width:") && !strings.Contains(bodyStyles, fmt.Sprintf("%dpx", width)) {
 			log.Printf("Warning: Template '%s' has custom body width - may break layout constraints", viewName)
 		}
-		if strings.Contains(bodyStyles, "height:") && !strings.Contains(bodyStyles, "480px") {
+		if strings.Contains(bodyStyles, "height:") && !strings.Contains(bodyStyles, fmt.Sprintf("%dpx", height)) {
 			log.Printf("Warning: Template '%s' has custom body height - may break layout constraints", viewName)
 		}
 	}
@@ -212,25 +418,38 @@ func extractBodyStyles(html string) string {
 }
 
 func renderViewHTML(view View) (string, error) {
-	tmpl, err := template.ParseFiles(view.Template)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse template '%s': %w", view.Template, err)
-	}
-
 	viewData, err := loadViewData(view)
 	if err != nil {
 		return "", fmt.Errorf("failed to load data for view '%s': %w", view.Name, err)
 	}
 
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, viewData); err != nil {
-		return "", fmt.Errorf("failed to execute template '%s': %w", view.Name, err)
-	}
+	return executeViewTemplate(view, viewData)
+}
+
+// executeViewTemplate renders the view's template with already loaded data
+func executeViewTemplate(view View, viewData *ViewData) (string, error) {
+	var html string
+	if view.Engine == engineLiquid {
+		out, err := executeLiquidTemplate(view, viewData)
+		if err != nil {
+			return "", err
+		}
+		html = out
+	} else {
+		tmpl, err := template.ParseFiles(view.Template)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse template '%s': %w", view.Template, err)
+		}
 
-	html := buf.String()
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, viewData); err != nil {
+			return "", fmt.Errorf("failed to execute template '%s': %w", view.Name, err)
+		}
+		html = buf.String()
+	}
 	
 	// Validate template structure and constraints
-	if err := validateTemplate(html, view.Name); err != nil {
+	if err := validateTemplate(html, view.Name, viewData.Width, viewData.Height); err != nil {
 		return "", err
 	}
 	
@@ -255,9 +474,14 @@ func validateViewBeforeRender(view View) []string {
 		return warnings // Return early if template doesn't exist
 	}
 	
-	// Check data file exists
-	if _, err := os.Stat(view.DataPath); os.IsNotExist(err) {
-		warnings = append(warnings, fmt.Sprintf("Data file missing: %s", view.DataPath))
+	// Check data files exist
+	for _, source := range view.dataSources() {
+		if source.Type != sourceFile {
+			continue
+		}
+		if _, err := os.Stat(source.Path); os.IsNotExist(err) {
+			warnings = append(warnings, fmt.Sprintf("Data file missing: %s", source.Path))
+		}
 	}
 	
 	// Read template and check for common issues
@@ -265,25 +489,39 @@ func validateViewBeforeRender(view View) []string {
 	if err == nil {
 		content := string(templateContent)
 		
-		// Check for viewport meta tag
+		// Liquid plugin markup is wrapped in a page with the base styles at render time
+		if view.Engine == engineLiquid && !strings.Contains(strings.ToLower(content), "<html") {
+			return warnings
+		}
+		
+		// Check for viewport meta tag: the render size, or {{.Width}}/{{.Height}} to follow
+		// every output profile
 		viewportPattern := fmt.Sprintf(`width=%d, height=%d`, config.Render.Width, config.Render.Height)
 		if !strings.Contains(content, viewportPattern) && 
-		   !strings.Contains(content, "width=800, height=480") {
+		   !strings.Contains(content, "width={{.Width}}, height={{.Height}}") {
 			warnings = append(warnings, "Missing or incorrect viewport meta tag - should match render dimensions")
+		} else if !strings.Contains(content, "width={{.Width}}, height={{.Height}}") && rendersAtOtherSizes(view) {
+			// validateTemplate rejects the fixed size when the view is rendered at another one
+			warnings = append(warnings, fmt.Sprintf("Viewport is fixed at %dx%d but the view is rendered at other sizes (mashup regions or render.profiles) - use width={{.Width}}, height={{.Height}}",
+				config.Render.Width, config.Render.Height))
 		}
 		
 		// Check for styles injection
-		if !strings.Contains(content, "{{.Styles}}") {
+		if view.Engine == engineLiquid {
+			if !liquidStylesPattern.MatchString(content) {
+				warnings = append(warnings, "Missing {{ styles }} - base styles won't be applied automatically")
+			}
+		} else if !strings.Contains(content, "{{.Styles}}") {
 			warnings = append(warnings, "Missing {{.Styles}} - base styles won't be applied automatically")
 		}
 		
 		// Check for dangerous CSS patterns
 		if strings.Contains(content, "body {") {
 			bodyStyles := extractBodyStyles(content)
-			if strings.Contains(bodyStyles, "width:") && !strings.Contains(bodyStyles, "800") {
+			if strings.Contains(bodyStyles, "width:") && !strings.Contains(bodyStyles, fmt.Sprint(config.Render.Width)) {
 				warnings = append(warnings, "Custom body width detected - may break layout constraints (use base styles instead)")
 			}
-			if strings.Contains(bodyStyles, "height:") && !strings.Contains(bodyStyles, "480") {
+			if strings.Contains(bodyStyles, "height:") && !strings.Contains(bodyStyles, fmt.Sprint(config.Render.Height)) {
 				warnings = append(warnings, "Custom body height detected - may break layout constraints (use base styles instead)")
 			}
 			if strings.Contains(bodyStyles, "overflow:") && strings.Contains(bodyStyles, "visible") {
@@ -311,27 +549,67 @@ func validateViewBeforeRender(view View) []string {
 }
 
 func getCurrentView() View {
-	if len(views) == 0 {
-		initViews()
-	}
 	if len(views) == 0 {
 		return View{} // Empty view as fallback
 	}
 	return views[currentViewIndex]
 }
 
+// rendersAtOtherSizes reports whether the view is also rendered at a size other than
+// render.width x render.height: as a mashup child or for an output profile
+func rendersAtOtherSizes(view View) bool {
+	if len(config.Render.Profiles) > 0 {
+		return true
+	}
+	for _, other := range views {
+		if other.Mashup != nil && other.Mashup.contains(view.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// findView looks up a configured view by name, including views only shown in mashups
+func findView(name string) (View, bool) {
+	for _, view := range views {
+		if view.Name == name {
+			return view, true
+		}
+	}
+	for _, view := range views {
+		if view.Mashup == nil {
+			continue
+		}
+		for _, child := range view.Mashup.Children {
+			if child.Name == name {
+				return child, true
+			}
+		}
+	}
+	return View{}, false
+}
+
+// shouldRotate reports whether the schedule or the dwell time calls for a different view
 func shouldRotate() bool {
-	if len(views) <= 1 {
+	if len(views) == 0 {
 		return false
 	}
-	return time.Since(lastRotationTime) >= rotationInterval
+	if pinned, ok := pinnedIndex(); ok {
+		return pinned != currentViewIndex
+	}
+	return pickView(views, currentViewIndex, lastRotationTime, time.Now()) != currentViewIndex
 }
 
+// rotateView switches to the view that should be on screen now
 func rotateView() {
-	if len(views) <= 1 {
+	next := pickView(views, currentViewIndex, lastRotationTime, time.Now())
+	if pinned, ok := pinnedIndex(); ok {
+		next = pinned
+	}
+	if next == currentViewIndex {
 		return
 	}
-	currentViewIndex = (currentViewIndex + 1) % len(views)
+	currentViewIndex = next
 	lastRotationTime = time.Now()
 	log.Printf("Rotated to view: %s (index %d)", views[currentViewIndex].Name, currentViewIndex)
 }
@@ -346,17 +624,11 @@ func getCurrentImagePath() string {
 
 // generateTemplateBoilerplate creates a new template file with proper structure
 func generateTemplateBoilerplate(templateName string) {
-	// Load config if not already loaded
-	if config.Render.Width == 0 {
-		config.Render.Width = 800
-		config.Render.Height = 480
-	}
-	
 	boilerplate := fmt.Sprintf(`<!DOCTYPE html>
 <html lang="en">
 <head>
   <meta charset="UTF-8">
-  <meta name="viewport" content="width=%d, height=%d, initial-scale=1.0">
+  <meta name="viewport" content="width={{.Width}}, height={{.Height}}, initial-scale=1.0">
   <title>%s</title>
   <style>
 {{.Styles}}
@@ -401,7 +673,7 @@ func generateTemplateBoilerplate(templateName string) {
     -->
   </div>
 </body>
-</html>`, config.Render.Width, config.Render.Height, templateName)
+</html>`, templateName)
 	
 	// Create templates directory if it doesn't exist
 	templateDir := "./templates"
@@ -452,18 +724,17 @@ func generateTemplateBoilerplate(templateName string) {
 	log.Printf("\nNext steps:")
 	log.Printf("1. Edit the template: %s", templatePath)
 	log.Printf("2. Edit the data file: %s", dataPath)
-	log.Printf("3. Add view to initViews() in views.go:")
+	log.Printf("3. Add the view to the \"views\" section of config.json:")
 	log.Printf("   {")
-	log.Printf("       Name:     \"%s\",", templateName)
-	log.Printf("       Template: \"./templates/%s.html\",", templateName)
-	log.Printf("       DataPath: \"./data/%s.json\",", templateName)
+	log.Printf("     \"name\": \"%s\",", templateName)
+	log.Printf("     \"template\": \"./templates/%s.html\",", templateName)
+	log.Printf("     \"dataPath\": \"./data/%s.json\"", templateName)
 	log.Printf("   }")
+	log.Printf("The server picks up the new view without a restart")
 }
 
 // validateAllTemplates validates all registered templates and reports issues
 func validateAllTemplates() {
-	initViews()
-	
 	if len(views) == 0 {
 		log.Println("No views configured.")
 		return
@@ -475,7 +746,9 @@ func validateAllTemplates() {
 	for _, view := range views {
 		log.Printf("\n📋 Template: %s", view.Name)
 		log.Printf("   Template: %s", view.Template)
-		log.Printf("   Data:     %s", view.DataPath)
+		for _, source := range view.dataSources() {
+			log.Printf("   Data:     %s", source.describe())
+		}
 		
 		warnings := validateViewBeforeRender(view)
 		if len(warnings) > 0 {
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// maxWebhookBody bounds the JSON accepted by /api/views/<name>/data
+const maxWebhookBody = 1 << 20
+
+// WebhookStore keeps data pushed to views that declare a "webhook" source. It lives in
+// memory only; pushers are expected to send their state again after a restart.
+type WebhookStore struct {
+	mu   sync.Mutex
+	data map[string]map[string]interface{} // By view name
+}
+
+var webhookStore = &WebhookStore{data: make(map[string]map[string]interface{})}
+
+// Get returns a copy of the data pushed to a view
+func (s *WebhookStore) Get(view string) (map[string]interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.data[view]
+	if !ok {
+		return nil, false
+	}
+	return cloneJSONValue(data).(map[string]interface{}), true
+}
+
+// Update replaces the view's data, or deep-merges into it when merge is set
+func (s *WebhookStore) Update(view string, data map[string]interface{}, merge bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.data[view]; ok && merge {
+		mergeData(existing, data, mergeDeep)
+		return
+	}
+	s.data[view] = data
+}
+
+// hasWebhookSource reports whether the view reads pushed data from the webhook store
+func (v View) hasWebhookSource() bool {
+	for _, source := range v.Sources {
+		if source.Type == sourceWebhook {
+			return true
+		}
+	}
+	return false
+}
+
+// handleViewData serves POST (replace) and PATCH (deep merge) /api/views/<name>/data.
+// Add ?render=1 to re-render the view right away. GET returns the stored data.
+func handleViewData(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	name, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/views/"), "/data")
+	if !ok || name == "" || strings.Contains(name, "/") {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Not found"})
+		return
+	}
+	if r.Method == http.MethodGet {
+		serveViewData(w, name)
+		return
+	}
+	if r.Method != http.MethodPost && r.Method != http.MethodPatch {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !requireToken(w, r, "Webhooks") {
+		return
+	}
+
+	view, ok := findView(name)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Unknown view: " + name})
+		return
+	}
+
+	var data map[string]interface{}
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err := decoder.Decode(&data); err != nil || data == nil {
+		if err == nil {
+			err = fmt.Errorf("expected a JSON object")
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid JSON: " + err.Error()})
+		return
+	}
+
+	merge := r.Method == http.MethodPatch
+	var stored string
+	switch {
+	case view.hasWebhookSource():
+		webhookStore.Update(view.Name, data, merge)
+		stored = "memory"
+	case view.DataPath != "":
+		if err := writeViewDataFile(view.DataPath, data, merge); err != nil {
+			log.Printf("Warning: Webhook for view %s: %v", view.Name, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		stored = view.DataPath
+	default:
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "View has neither a dataPath nor a webhook source"})
+		return
+	}
+	log.Printf("Webhook updated data for view %s (%s, %d key(s))", view.Name, stored, len(data))
+
+	response := map[string]interface{}{
+		"success": true,
+		"view":    view.Name,
+		"stored":  stored,
+	}
+
+	if r.URL.Query().Get("render") != "" {
+		result, err := renderView(view)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			response["success"] = false
+			response["error"] = err.Error()
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		// Mashups showing the view are re-rendered too
+		current := currentRotation().view.Name
+		onScreen := current == view.Name
+		for _, other := range views {
+			if other.Mashup == nil || !other.Mashup.contains(view.Name) {
+				continue
+			}
+			if _, err := renderView(other); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+			onScreen = onScreen || current == other.Name
+		}
+		if onScreen {
+			if err := renderCurrentViewToTRMNL(); err != nil {
+				log.Printf("Warning: Failed to update screen after webhook: %v", err)
+			}
+		}
+		response["rendered"] = map[string]interface{}{
+			"dataFetchDuration":  result.DataDuration.String(),
+			"renderDuration":     result.HTMLDuration.String(),
+			"conversionDuration": result.ImageDuration.String(),
+			"outputSize":         result.Size,
+			"warnings":           result.Warnings,
+		}
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// requireToken checks the bearer token (server.webhookToken) of a request that changes
+// state, writing the error response when it is missing or wrong
+func requireToken(w http.ResponseWriter, r *http.Request, feature string) bool {
+	if config.Server.WebhookToken == "" {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": feature + " are disabled; set server.webhookToken in config.json"})
+		return false
+	}
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || subtle.ConstantTimeCompare([]byte(token), []byte(config.Server.WebhookToken)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid or missing bearer token"})
+		return false
+	}
+	return true
+}
+
+// serveViewData returns the data a webhook would replace: the webhook store or the dataPath
+// file. Other views return their merged data, marked read-only.
+func serveViewData(w http.ResponseWriter, name string) {
+	view, ok := findView(name)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Unknown view: " + name})
+		return
+	}
+
+	response := map[string]interface{}{
+		"view":     view.Name,
+		"editable": true,
+	}
+	switch {
+	case view.Mashup != nil:
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Mashups have no data of their own"})
+		return
+	case view.hasWebhookSource():
+		data, ok := webhookStore.Get(view.Name)
+		if !ok {
+			data = map[string]interface{}{}
+		}
+		response["stored"] = "memory"
+		response["data"] = data
+	case view.DataPath != "":
+		content, err := os.ReadFile(view.DataPath)
+		if err != nil && !os.IsNotExist(err) {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		var data interface{} = map[string]interface{}{}
+		if len(content) > 0 {
+			if err := json.Unmarshal(content, &data); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("failed to parse %s: %v", view.DataPath, err)})
+				return
+			}
+		}
+		response["stored"] = view.DataPath
+		response["data"] = data
+	default:
+		data, err := loadViewSources(view)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		response["editable"] = false
+		response["data"] = data
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// viewDataFileMu serializes read-modify-write cycles on view data files
+var viewDataFileMu sync.Mutex
+
+// writeViewDataFile atomically replaces a view's JSON data file (or deep-merges into it)
+func writeViewDataFile(path string, data map[string]interface{}, merge bool) error {
+	viewDataFileMu.Lock()
+	defer viewDataFileMu.Unlock()
+
+	if merge {
+		existing := make(map[string]interface{})
+		if content, err := os.ReadFile(path); err == nil {
+			if err := json.Unmarshal(content, &existing); err != nil {
+				return fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		mergeData(existing, data, mergeDeep)
+		data = existing
+	}
+
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode data: %w", err)
+	}
+
+	tmpPath := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err := os.WriteFile(tmpPath, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	noteSelfWrite(path)
+	return nil
+}
+
+// selfWrites remembers the fingerprint of files the server wrote itself, so the config
+// watcher doesn't re-render every view for a change that was already handled
+var (
+	selfWritesMu sync.Mutex
+	selfWrites   = make(map[string]string)
+)
+
+func noteSelfWrite(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	selfWritesMu.Lock()
+	selfWrites[filepath.Clean(path)] = fileFingerprint(info)
+	selfWritesMu.Unlock()
+}
+
+// onlySelfWrites reports whether every difference between two watch snapshots is a file
+// the server wrote itself
+func onlySelfWrites(current, last map[string]string) bool {
+	selfWritesMu.Lock()
+	defer selfWritesMu.Unlock()
+
+	for path, fingerprint := range current {
+		if last[path] != fingerprint && selfWrites[filepath.Clean(path)] != fingerprint {
+			return false
+		}
+	}
+	for path := range last {
+		if _, ok := current[path]; !ok {
+			return false
+		}
+	}
+	return true
+}